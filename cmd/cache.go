@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var showBody bool
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the web cache",
+	Long: `Pages fetched from Banner are cached on disk so repeated runs don't hit
+the server again. Each class of page (isq, schedule, department, catalog,
+seats, other) expires after its own lifetime, which can be changed with
+--cache-ttl.`,
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the web cache by class of page",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ttls := cacheTtl()
+		stats, err := webCache().Stats(scrape.PageClass)
+		if err != nil {
+			panic(err)
+		}
+
+		var classes []string
+		for class := range stats {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CLASS\tENTRIES\tEXPIRED\tSIZE\tOLDEST\tTTL")
+		for _, class := range classes {
+			s := stats[class]
+			if class == "" {
+				fmt.Fprintf(w, "(unreadable)\t%d\t%d\t%s\t\t\n", s.Entries, s.Entries, formatBytes(s.Bytes))
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%v\n", class, s.Entries, s.Expired, formatBytes(s.Bytes),
+				s.Oldest.Format("2006-01-02 15:04"), ttls[class])
+		}
+		_ = w.Flush()
+	},
+}
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired and unreadable entries from the web cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		removed, freed, err := webCache().Prune()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Removed %d entries (%s)\n", removed, formatBytes(freed))
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every entry from the web cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cache := webCache()
+		if err := cache.Clear(); err != nil {
+			panic(err)
+		}
+		fmt.Println("Cleared", cache.Dir)
+	},
+}
+
+// cacheInspectCmd represents the cache inspect command
+var cacheInspectCmd = &cobra.Command{
+	Use:   "inspect URL",
+	Short: "Show the cached responses for a URL",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cache := webCache()
+		entries, err := cache.Find(args[0])
+		if err != nil {
+			panic(err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("Not cached:", args[0])
+			os.Exit(1)
+		}
+
+		for i, e := range entries {
+			if i > 0 {
+				fmt.Println()
+			}
+			expires := e.FetchedAt.Add(cache.TTL(e.URL))
+			fmt.Println("File:   ", e.File)
+			fmt.Println("Request:", e.Method, e.URL)
+			if len(e.Form) > 0 {
+				fmt.Println("Form:   ", string(e.Form))
+			}
+			fmt.Println("Class:  ", scrape.PageClass(e.URL))
+			fmt.Println("Status: ", e.StatusCode)
+			fmt.Println("Fetched:", e.FetchedAt.Format(time.RFC1123))
+			if cache.Expired(e) {
+				fmt.Println("Expired:", expires.Format(time.RFC1123))
+			} else {
+				fmt.Println("Expires:", expires.Format(time.RFC1123))
+			}
+			fmt.Println("Size:   ", formatBytes(e.Size))
+			if showBody {
+				fmt.Println()
+				fmt.Println(string(e.Body))
+			}
+		}
+	},
+}
+
+// formatBytes formats a size like "1.2 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheInspectCmd)
+
+	cacheInspectCmd.Flags().BoolVar(&showBody, "body", false, "Print the cached response body (default: false)")
+}
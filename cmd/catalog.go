@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var catalogTerm string
+var refreshCatalog bool
+
+// catalogCmd represents the catalog command
+var catalogCmd = &cobra.Command{
+	Use:   "catalog [course]...",
+	Short: "Show the catalog entries of courses",
+	Long: `Given some course names this command will output their catalog
+entries: the title, description, credits, levels, schedule types,
+department, attributes, restrictions, prerequisites, and corequisites.
+
+Entries are read from the local database, which sync fills in. Courses
+that aren't there yet (or all of them, with --refresh) are scraped from
+the catalog of --term.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sqlite := openSqlite()
+		defer sqlite.Close()
+
+		stored, err := sqlite.LoadCatalog(args)
+		if err != nil {
+			panic(err)
+		}
+		entries := make(map[string]scrape.CatalogEntry)
+		for _, entry := range stored {
+			entries[entry.Name] = entry
+		}
+		var missing []string
+		for _, name := range args {
+			if _, found := entries[name]; !found || refreshCatalog {
+				missing = append(missing, name)
+			}
+		}
+
+		// Scrape the entries we don't have
+		if len(missing) > 0 {
+			if catalogTerm == "" {
+				log.Fatalln("Not in the local database:", missing, "- give a --term to scrape them from")
+			}
+			term, err := scrape.ParseTerm(catalogTerm)
+			if err != nil {
+				panic(err)
+			}
+			scraped := make([]scrape.CatalogEntry, len(missing))
+			err = parallel(len(missing), func(i int) (err error) {
+				scraped[i], err = scrape.GetCatalogEntry(c.Clone(), missing[i], term)
+				if err == scrape.ErrNotInCatalog {
+					log.Printf("Warning: %s is not in the %s catalog", missing[i], term)
+					return nil
+				}
+				return err
+			})
+			if err != nil {
+				panic(err)
+			}
+			var found []scrape.CatalogEntry
+			for _, entry := range scraped {
+				if entry.Title != "" {
+					found = append(found, entry)
+					entries[entry.Name] = entry
+				}
+			}
+			if err := sqlite.SaveCatalog(found); err != nil {
+				panic(fmt.Errorf("failed to save catalog: %v", err))
+			}
+		}
+
+		var rows []scrape.CatalogEntry
+		for _, name := range args {
+			if entry, found := entries[name]; found {
+				rows = append(rows, entry)
+			}
+		}
+		format, fileName := parseOutputFlags("catalog")
+		if err := report.WriteFormat(rows, format, fileName); err != nil {
+			panic(err)
+		}
+		log.Println("Wrote to file", fileName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+
+	addOutputFlags(catalogCmd)
+	catalogCmd.Flags().StringVar(&catalogTerm, "term", "", "Term whose catalog courses are scraped from (e.g. \"Fall 2023\")")
+	catalogCmd.Flags().BoolVar(&refreshCatalog, "refresh", false, "Scrape every course again, even those in the local database (default: false)")
+}
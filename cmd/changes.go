@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var changesSince string
+
+// changesCmd represents the changes command
+var changesCmd = &cobra.Command{
+	Use:   "changes [department] [term]",
+	Short: "List the changes to a department's schedule",
+	Long: `Given a department ID and a term (such as "Fall 2023") this command
+will list how the department's sections changed between syncs: sections
+added or removed, and changes to their status, title, instructor, credits,
+part of term, meeting times, rooms, campus, and approval.
+
+Changes are read from the history sync keeps in the local database, so
+only changes between syncs are seen, and the first sync of a term is where
+its history starts. Use --since to only list changes made on or after a
+date, and --format or --output to also write them to a file.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		deptId, err := strconv.Atoi(args[0]) // e.g. 6502
+		if err != nil {
+			panic(fmt.Errorf("%s is not a valid department: %v", args[0], err))
+		}
+		term, err := scrape.ParseTerm(args[1])
+		if err != nil {
+			panic(err)
+		}
+		var since time.Time
+		if changesSince != "" {
+			if since, err = time.ParseInLocation("2006-01-02", changesSince, time.Local); err != nil {
+				panic(fmt.Errorf("%s is not a valid date (e.g. 2023-08-01): %v", changesSince, err))
+			}
+		}
+
+		sqlite := openSqlite()
+		versions, err := sqlite.LoadHistory(deptId, []scrape.Term{term})
+		_ = sqlite.Close()
+		if err != nil {
+			panic(err)
+		}
+		if len(versions) == 0 {
+			log.Fatalln("No history found for department", deptId, "in", term, "- run `isqool sync` first")
+		}
+
+		changes := report.DescribeChanges(versions, since)
+		if output != "-" {
+			if err := report.WriteChangeTable(os.Stdout, changes); err != nil {
+				panic(err)
+			}
+		}
+
+		// Write the changes to a file if asked to
+		if output != "" || cmd.Flags().Changed("format") {
+			termId, _ := term.Id()
+			format, fileName := parseOutputFlags(fmt.Sprintf("%d_%d_changes", deptId, termId))
+			if err := report.WriteFormat(changes, format, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changesCmd)
+
+	addOutputFlags(changesCmd)
+
+	changesCmd.Flags().StringVar(&changesSince, "since", "", "Only list changes made on or after this date (e.g. 2023-08-01)")
+}
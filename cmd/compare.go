@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var compareCsv bool
+var compareJson bool
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare [course]",
+	Short: "Compare the instructors who have taught a course",
+	Long: `Given a course name this command will summarize each instructor
+who has taught it: the enrollment-weighted rating and its 95% confidence
+interval, the average GPA, the D/F rate, the number of sections taught,
+and the first and last terms taught.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0] // COP2220 etc.
+
+		isqs, grades, err := scrape.GetIsqAndGrades(c.Clone(), name, false)
+		if err != nil {
+			panic(err)
+		}
+		summaries := report.CompareInstructors(report.CourseInput{
+			Isqs:   isqs,
+			Grades: grades,
+		})
+
+		if err := report.WriteInstructorTable(os.Stdout, summaries); err != nil {
+			panic(err)
+		}
+		if compareCsv {
+			fileName := outputPath(name + "_instructors.csv")
+			if err := report.WriteCsv(summaries, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+		if compareJson {
+			fileName := outputPath(name + "_instructors.json")
+			if err := report.WriteJson(summaries, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().BoolVar(&compareCsv, "csv", false, "Also write the comparison to a CSV file (default: false)")
+	compareCmd.Flags().BoolVar(&compareJson, "json", false, "Also write the comparison to a JSON file (default: false)")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/openswoop/isqool/pkg/daemon"
+
+	"github.com/spf13/cobra"
+)
+
+// stateFile is where the daemon remembers its runs, next to the database
+const stateFile = "daemon-state.json"
+
+var daemonJobs string
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Sync departments on a schedule",
+	Long: `Runs in the foreground, syncing the departments listed in a JSON jobs
+file on their cron schedules, for example:
+
+  {
+    "max_concurrent": 1,
+    "jitter": "10m",
+    "addr": ":8081",
+    "departments": [
+      {"department": 6502, "terms": ["Fall 2023"], "schedule": "0 3 * * *"}
+    ]
+  }
+
+The time of each department's last successful sync is remembered across
+restarts, and the health and metrics of the syncs are served at /healthz
+and /metrics.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := daemon.LoadConfig(daemonJobs)
+		if err != nil {
+			panic(err)
+		}
+
+		d, err := daemon.New(config, syncDepartment, filepath.Join(filepath.Dir(cfg.Database), stateFile))
+		if err != nil {
+			panic(err)
+		}
+
+		srv := &http.Server{Addr: config.Addr, Handler: d.Handler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+
+		log.Printf("Scheduling %d departments, serving metrics on %s", len(config.Departments), config.Addr)
+		d.Start()
+
+		// Wait for running syncs to finish before exiting
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		log.Println("Stopping...")
+		d.Stop()
+		_ = srv.Shutdown(context.Background())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	userConfigDir, _ := os.UserConfigDir()
+	daemonCmd.Flags().StringVar(&daemonJobs, "jobs", userConfigDir+"/isqool/daemon.json", "File listing the departments to sync")
+	daemonCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Run without modifying the database (default: false)")
+}
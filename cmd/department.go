@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+// departmentCmd represents the department command
+var departmentCmd = &cobra.Command{
+	Use:   "department [department] [term]...",
+	Short: "Summarize a department from the local database",
+	Long: `Given a department ID and optionally some terms (such as "Spring 2020")
+this command will summarize each course and instructor in the department by
+term: the number of sections, enrollment, wait list, average rating, average
+GPA, and how many sections were in person, hybrid, or online.
+
+The summary is built from the local database without scraping anything, so
+the department must have been synced first.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		deptId, err := strconv.Atoi(args[0]) // e.g. 6502
+		if err != nil {
+			panic(fmt.Errorf("%s is not a valid department: %v", args[0], err))
+		}
+		var terms []scrape.Term
+		for _, arg := range args[1:] {
+			term, err := scrape.ParseTerm(arg)
+			if err != nil {
+				panic(err)
+			}
+			terms = append(terms, term)
+		}
+
+		sqlite := openSqlite()
+		schedules, err := sqlite.LoadDepartments(deptId, terms)
+		if err != nil {
+			panic(err)
+		}
+		if len(schedules) == 0 {
+			log.Fatalln("No data found for department", deptId, "- run `isqool sync` first")
+		}
+
+		seen := make(map[string]bool)
+		var courses []string
+		for _, row := range schedules {
+			if !seen[row.Name] {
+				courses = append(courses, row.Name)
+				seen[row.Name] = true
+			}
+		}
+		isqs, err := sqlite.LoadIsqs(courses)
+		if err != nil {
+			panic(err)
+		}
+		grades, err := sqlite.LoadGrades(courses)
+		if err != nil {
+			panic(err)
+		}
+		_ = sqlite.Close()
+
+		summaries := report.SummarizeDepartment(report.DepartmentInput{
+			Schedules: schedules,
+			Isqs:      isqs,
+			Grades:    grades,
+		})
+
+		format, fileName := parseOutputFlags(fmt.Sprintf("%d_summary", deptId))
+		if err := report.WriteFormat(summaries, format, fileName); err != nil {
+			panic(err)
+		}
+		log.Println("Wrote to file", fileName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(departmentCmd)
+
+	addOutputFlags(departmentCmd)
+}
@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"regexp"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/openswoop/isqool/pkg/database"
 	"github.com/openswoop/isqool/pkg/report"
@@ -16,78 +19,348 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var dbFile = "/isqool/isqool.db"
+var fullJoin bool
+var reconcileReport string
+var html bool
+var fetchFile string
+var merge bool
+var subjects []string
+var subjectTerm string
 
 // fetchCmd represents the fetch command
 var fetchCmd = &cobra.Command{
-	Use:   "fetch [course|professor]",
-	Short: "Scrape summary data to a CSV file",
+	Use:   "fetch [course|professor]...",
+	Short: "Scrape summary data to a file",
 	Long: `Given a course name or professor's N# this command will output
 a CSV file from the historical course data available. The
-results will also be inserted into a local SQLite database.`,
-	Args: cobra.ExactArgs(1),
+results will also be inserted into a local SQLite database.
+
+Several courses and professors can be fetched in one run, given as
+arguments or one per line in a file (--file, or - for stdin). A glob like
+COP* fetches every matching course in the local database, while
+--subject COP --term "Fall 2023" fetches every COP course in that term's
+catalog, or the latest term in the local database if --term is left out.
+Each is written to its own file unless --merge is given, which writes
+them all to one.
+
+Use --format to write JSON, NDJSON, Parquet, XLSX, or Markdown
+instead, and --output to choose the file (or - for stdout).`,
 	Run: func(cmd *cobra.Command, args []string) {
-		name := args[0] // COT3100 or N00474503 etc.
-		isProfessor, _ := regexp.MatchString("N\\d{8}", name)
-		// Professor name is present
-		if !isProfessor && len(name) > 7 {
-			// Adds name to search term Replace space with %20
-			url := fmt.Sprintf("https://webapps.unf.edu/faculty/bio/api/v1/faculty?searchLimit=1&searchTerm=%v", strings.ReplaceAll(name, " ", "%20")) 
-			log.Println(url)
-			response, error := http.Get(url)
-			if error != nil {
-				panic(error)
+		sqlite := openSqlite()
+		defer sqlite.Close()
+
+		names, err := fetchNames(sqlite, args)
+		if err != nil {
+			panic(err)
+		}
+		if len(names) == 0 {
+			log.Fatalln("Nothing to fetch: give a course or professor, --file, or --subject")
+		}
+		if html && merge {
+			log.Fatalln("--html can't be combined with --merge")
+		}
+		if output != "" && !merge && len(names) > 1 {
+			log.Fatalln("--output needs --merge when fetching more than one course or professor")
+		}
+
+		results := make([]fetchResult, len(names))
+		errs := parallelEach(len(names), func(i int) error {
+			var err error
+			results[i], err = fetchOne(sqlite, names[i])
+			return err
+		})
+
+		var reconciliations []report.Reconciliation
+		var inputs []report.CourseInput
+		for i, result := range results {
+			if errs[i] == nil {
+				reconciliations = append(reconciliations, result.reconciliations...)
+				inputs = append(inputs, result.input)
+			}
+		}
+		if reconcileReport != "" {
+			if err := report.WriteCsv(reconciliations, reconcileReport); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote reconciliation report to file", reconcileReport)
+		}
+		if merge && len(inputs) > 0 {
+			format, fileName := parseOutputFlags("fetch")
+			if err := report.WriteCourse(fileName, format, mergeInputs(inputs)); err != nil {
+				panic(err)
 			}
-			defer response.Body.Close()
-			body, error := io.ReadAll(response.Body)
-			if error != nil {
-				panic(error)
+			log.Println("Wrote to file", fileName)
+		}
+
+		// Report how each one went
+		if len(names) == 1 && errs[0] != nil {
+			panic(errs[0])
+		}
+		failed := 0
+		w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tISQS\tGRADES\tSCHEDULES\tDETAIL")
+		for i, result := range results {
+			if errs[i] != nil {
+				failed++
+				fmt.Fprintf(w, "%s\tfailed\t-\t-\t-\t%v\n", names[i], errs[i])
+				continue
 			}
-			re := regexp.MustCompile("N\\d{8}")
-			name = re.FindString(string(body))
-			isProfessor = true
+			fmt.Fprintf(w, "%s\tok\t%d\t%d\t%d\t%s\n", result.name, len(result.input.Isqs),
+				len(result.input.Grades), len(result.input.Schedules), result.file)
+		}
+		_ = w.Flush()
+		if failed > 0 {
+			log.Fatalf("%d of %d failed", failed, len(names))
 		}
-		log.Println(name)
-		// Scrape the data
-		isqs, grades, err := scrape.GetIsqAndGrades(c.Clone(), name, isProfessor)
+	},
+}
+
+// fetchResult is what was fetched for one course or professor
+type fetchResult struct {
+	name            string
+	input           report.CourseInput
+	reconciliations []report.Reconciliation
+	file            string
+}
+
+// fetchNames collects the courses and professors to fetch from args,
+// --file, and --subject, expanding globs against the courses in the local
+// database
+func fetchNames(sqlite database.Sqlite, args []string) ([]string, error) {
+	if fetchFile != "" {
+		lines, err := readLines(fetchFile)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+		args = append(args, lines...)
+	}
+	if len(subjects) > 0 {
+		var term scrape.Term
+		var err error
+		if subjectTerm != "" {
+			term, err = scrape.ParseTerm(subjectTerm)
+		} else {
+			term, err = latestTerm(sqlite)
 		}
-		params := scrape.CollectScheduleParams(isqs, grades)
-		schedules, err := scrape.GetSchedules(c.Clone(), params)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		log.Println("Found", len(schedules), "records")
+		for _, subject := range subjects {
+			courses, err := scrape.ListCourses(c.Clone(), subject, term)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s courses: %v", subject, err)
+			}
+			if len(courses) == 0 {
+				return nil, fmt.Errorf("no %s courses in the %s catalog", subject, term)
+			}
+			log.Println("Found", len(courses), subject, "courses in the", term, "catalog")
+			args = append(args, courses...)
+		}
+	}
 
-		// Save all the data to the database
-		userCacheDir, _ := os.UserCacheDir()
-		sqlite := database.NewSqlite(userCacheDir + dbFile)
-		if err := sqlite.SaveIsqs(isqs); err != nil {
-			panic(err)
+	var courses []string
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			names = append(names, name)
+			seen[name] = true
 		}
-		if err := sqlite.SaveGrades(grades); err != nil {
-			panic(err)
+	}
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			add(arg)
+			continue
 		}
-		if err := sqlite.SaveSchedules(schedules); err != nil {
-			panic(err)
+		if courses == nil {
+			var err error
+			if courses, err = sqlite.Courses(); err != nil {
+				return nil, err
+			}
+		}
+		matched := false
+		for _, course := range courses {
+			if ok, err := path.Match(arg, course); err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %v", arg, err)
+			} else if ok {
+				add(course)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no courses in the local database match %s", arg)
 		}
-		_ = sqlite.Close()
-		log.Println("Saved to database", dbFile)
+	}
+	return names, nil
+}
 
-		// Write to CSV
-		err = report.WriteCourse(name, report.CourseInput{
-			Isqs:      isqs,
-			Grades:    grades,
-			Schedules: schedules,
-		})
+// readLines reads the non-blank lines of a file, or stdin if it's -,
+// ignoring # comments
+func readLines(file string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		log.Println("Wrote to file", name+".csv")
-	},
+		defer f.Close()
+		r = f
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// fetchOne scrapes a course or professor, saves it to the database, and
+// unless --merge is given writes its report
+func fetchOne(sqlite database.Sqlite, name string) (fetchResult, error) {
+	result := fetchResult{name: name} // COT3100 or N00474503 etc.
+	isProfessor, _ := regexp.MatchString("N\\d{8}", name)
+	isCourse, _ := regexp.MatchString(`^[A-Z]{3}\d{4}[A-Z]?$`, name)
+	// Professor name is present
+	if !isProfessor && !isCourse && len(name) > 7 {
+		var err error
+		if result.name, err = lookupProfessor(name); err != nil {
+			return result, err
+		}
+		isProfessor = true
+	}
+	name = result.name
+	log.Println(name)
+
+	// Scrape the data
+	isqs, grades, err := scrape.GetIsqAndGrades(c.Clone(), name, isProfessor)
+	if err != nil {
+		return result, err
+	}
+	params := scrape.CollectScheduleParams(isqs, grades)
+	schedules, err := scrape.GetSchedules(c.Clone(), params)
+	if err != nil {
+		return result, err
+	}
+	log.Println("Found", len(schedules), "records for", name)
+
+	// Save all the data to the database
+	if err := sqlite.SaveIsqs(isqs); err != nil {
+		return result, err
+	}
+	if err := sqlite.SaveGrades(grades); err != nil {
+		return result, err
+	}
+	if err := sqlite.SaveSchedules(schedules); err != nil {
+		return result, err
+	}
+	log.Println("Saved", name, "to database", cfg.Database)
+
+	result.input = report.CourseInput{
+		Isqs:      isqs,
+		Grades:    grades,
+		Schedules: schedules,
+		FullJoin:  fullJoin,
+	}
+
+	// Summarize the rows that couldn't be joined exactly
+	_, result.reconciliations = report.Reconcile(result.input)
+	matches := make(map[report.Match]int)
+	for _, r := range result.reconciliations {
+		matches[r.Match]++
+	}
+	if len(result.reconciliations) > 0 {
+		log.Printf("Warning: %s: %d rows matched on normalized instructor, %d on section only, %d unmatched",
+			name, matches[report.MatchNormalized], matches[report.MatchSection], matches[report.MatchNone])
+	}
+	if merge {
+		return result, nil
+	}
+
+	// Write the report
+	if html {
+		result.file = output
+		if result.file == "" {
+			result.file = outputPath(name + ".html")
+		}
+		if err := report.WriteCourseHtml(result.file, name, result.input); err != nil {
+			return result, err
+		}
+		log.Println("Wrote to file", result.file)
+		return result, nil
+	}
+	format, fileName := parseOutputFlags(name)
+	result.file = fileName
+	if err := report.WriteCourse(fileName, format, result.input); err != nil {
+		return result, err
+	}
+	log.Println("Wrote to file", fileName)
+	return result, nil
+}
+
+// lookupProfessor finds the N# of a professor by name
+func lookupProfessor(name string) (string, error) {
+	// Adds name to search term Replace space with %20
+	url := fmt.Sprintf("https://webapps.unf.edu/faculty/bio/api/v1/faculty?searchLimit=1&searchTerm=%v", strings.ReplaceAll(name, " ", "%20"))
+	log.Println(url)
+	response, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	re := regexp.MustCompile("N\\d{8}")
+	nNumber := re.FindString(string(body))
+	if nNumber == "" {
+		return "", fmt.Errorf("no professor found named %s", name)
+	}
+	return nNumber, nil
+}
+
+// mergeInputs combines the data fetched for several courses and professors,
+// dropping the sections fetched more than once
+func mergeInputs(inputs []report.CourseInput) report.CourseInput {
+	merged := report.CourseInput{FullJoin: fullJoin}
+	isqs := make(map[scrape.Course]bool)
+	grades := make(map[scrape.Course]bool)
+	schedules := make(map[scrape.Course]bool)
+	for _, input := range inputs {
+		for _, isq := range input.Isqs {
+			if !isqs[isq.Course] {
+				merged.Isqs = append(merged.Isqs, isq)
+				isqs[isq.Course] = true
+			}
+		}
+		for _, grade := range input.Grades {
+			if !grades[grade.Course] {
+				merged.Grades = append(merged.Grades, grade)
+				grades[grade.Course] = true
+			}
+		}
+		for _, schedule := range input.Schedules {
+			if !schedules[schedule.Course] {
+				merged.Schedules = append(merged.Schedules, schedule)
+				schedules[schedule.Course] = true
+			}
+		}
+	}
+	return merged
 }
 
 func init() {
 	rootCmd.AddCommand(fetchCmd)
+
+	addOutputFlags(fetchCmd)
+	fetchCmd.Flags().BoolVar(&fullJoin, "full-join", false, "Include sections with grades or a schedule but no ISQ (default: false)")
+	fetchCmd.Flags().BoolVar(&html, "html", false, "Write a standalone HTML report with charts instead (default: false)")
+	fetchCmd.Flags().StringVar(&reconcileReport, "reconcile-report", "", "Write the rows that didn't match exactly to this CSV file")
+	fetchCmd.Flags().StringVar(&fetchFile, "file", "", "Read courses and professors to fetch from this file, one per line (or - for stdin)")
+	fetchCmd.Flags().StringSliceVar(&subjects, "subject", nil, "Fetch every course in these subjects (e.g. COP), from the catalog of --term")
+	fetchCmd.Flags().StringVar(&subjectTerm, "term", "", "Term whose catalog --subject lists (e.g. \"Fall 2023\") (default: the latest term in the local database)")
+	fetchCmd.Flags().BoolVar(&merge, "merge", false, "Write everything fetched to one file instead of one per course or professor (default: false)")
 }
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/openswoop/isqool/pkg/server"
+
+	"github.com/spf13/cobra"
+)
+
+// graphqlCmd represents the graphql command
+var graphqlCmd = &cobra.Command{
+	Use:   "graphql",
+	Short: "Serve the course data over GraphQL",
+	Long: `Serves the data in the local database over a GraphQL endpoint at
+/graphql. Courses, instructors, sections, ISQs, grades, schedules, and
+department meetings can be queried as one nested graph.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sqlite := openSqlite()
+		defer sqlite.Close()
+
+		http.Handle("/graphql", server.NewGraphql(sqlite))
+		log.Println("Listening on", graphqlAddr)
+		log.Fatal(http.ListenAndServe(graphqlAddr, nil))
+	},
+}
+
+var graphqlAddr string
+
+func init() {
+	rootCmd.AddCommand(graphqlCmd)
+
+	graphqlCmd.Flags().StringVar(&graphqlAddr, "addr", ":8080", "Address to listen on")
+}
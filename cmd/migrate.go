@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/openswoop/isqool/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Rebuild BigQuery tables into the partitioned layout",
+	Long: `Tables created by older versions of isqool are neither partitioned nor
+clustered. This command rebuilds them partitioned by term id and clustered
+by course, department, and instructor. The original tables are kept as
+backups with a "_backup_<timestamp>" suffix.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		bq, err := database.NewBigQuery(cfg.Project, cfg.Dataset)
+		if err != nil {
+			panic(fmt.Errorf("failed to connect to bigquery: %v", err))
+		}
+		defer bq.Close()
+		if err := bq.MigratePartitions(); err != nil {
+			panic(fmt.Errorf("failed to migrate tables: %v", err))
+		}
+		fmt.Println("Done.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
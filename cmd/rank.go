@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var rankPrior string
+var rankPriorWeight float64
+var rankLimit int
+var rankCsv bool
+var rankJson bool
+
+// rankCmd represents the rank command
+var rankCmd = &cobra.Command{
+	Use:   "rank [course|professor]...",
+	Short: "Rank sections by their adjusted rating",
+	Long: `Given one or more course names or professors' N#s this command will
+rank every section by its Bayesian adjusted rating. Each section's ISQ
+responses are shrunk towards the mean rating of its course (or of every
+section given, with --prior all), so sections with only a handful of
+responses can't outrank sections with many.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		level, err := report.ParsePriorLevel(rankPrior)
+		if err != nil {
+			panic(err)
+		}
+
+		var input report.CourseInput
+		for _, name := range args {
+			isProfessor, _ := regexp.MatchString("N\\d{8}", name)
+			isqs, grades, err := scrape.GetIsqAndGrades(c.Clone(), name, isProfessor)
+			if err != nil {
+				panic(err)
+			}
+			input.Isqs = append(input.Isqs, isqs...)
+			input.Grades = append(input.Grades, grades...)
+		}
+
+		ranked := report.RankSections(input, level, rankPriorWeight)
+		if rankLimit > 0 && len(ranked) > rankLimit {
+			ranked = ranked[:rankLimit]
+		}
+
+		if err := report.WriteRankingTable(os.Stdout, ranked); err != nil {
+			panic(err)
+		}
+		name := strings.Join(args, "_")
+		if rankCsv {
+			fileName := outputPath(name + "_ranking.csv")
+			if err := report.WriteCsv(ranked, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+		if rankJson {
+			fileName := outputPath(name + "_ranking.json")
+			if err := report.WriteJson(ranked, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rankCmd)
+
+	rankCmd.Flags().StringVar(&rankPrior, "prior", "course", "Sections to shrink towards: course or all")
+	rankCmd.Flags().Float64Var(&rankPriorWeight, "prior-weight", 0, "Number of pseudo-responses given to the prior (default: average responses per section)")
+	rankCmd.Flags().IntVar(&rankLimit, "limit", 0, "Only show the top N sections (default: all)")
+	rankCmd.Flags().BoolVar(&rankCsv, "csv", false, "Also write the ranking to a CSV file (default: false)")
+	rankCmd.Flags().BoolVar(&rankJson, "json", false, "Also write the ranking to a JSON file (default: false)")
+}
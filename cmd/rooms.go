@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var roomBuildings []string
+var roomDays string
+var roomOpen string
+var roomClose string
+var roomSlot time.Duration
+var roomMinIdle time.Duration
+var roomIdle bool
+var roomOccupancy bool
+var roomGrid bool
+
+// roomsCmd represents the rooms command
+var roomsCmd = &cobra.Command{
+	Use:   "rooms [term] [department]...",
+	Short: "Analyze how rooms are used in a term",
+	Long: `Given a term (such as "Fall 2023") and optionally some department IDs
+this command will work out how the rooms the departments' sections meet in
+are used each week: the hours each room is booked and idle while open, and
+the rooms double-booked by sections meeting at the same time.
+
+Rooms are read from the department schedules in the local database, every
+synced department by default, so only their sections are seen. Meetings
+without a room or time, and one-off meetings like exams, are left out.
+
+Use --idle to also list the blocks of time rooms sit idle, --occupancy to
+write how many of each building's rooms are in use in each time slot, and
+--grid to write a weekly grid of the rooms in use for each building.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		term, err := scrape.ParseTerm(args[0])
+		if err != nil {
+			panic(err)
+		}
+		opts := report.RoomOptions{
+			Days:     strings.ToUpper(roomDays),
+			DayStart: parseClock(roomOpen),
+			DayEnd:   parseClock(roomClose),
+			Slot:     int(roomSlot.Minutes()),
+			MinIdle:  int(roomMinIdle.Minutes()),
+		}
+		if opts.DayEnd <= opts.DayStart {
+			log.Fatalln("--close must be after --open")
+		}
+		if opts.Days == "" || strings.Trim(opts.Days, "MTWRFSU") != "" {
+			log.Fatalln("--days must be made of the days M, T, W, R, F, S, and U")
+		}
+		if opts.Slot <= 0 {
+			log.Fatalln("--slot must be at least a minute")
+		}
+
+		sqlite := openSqlite()
+		var depts []int
+		for _, arg := range args[1:] {
+			deptId, err := strconv.Atoi(arg) // e.g. 6502
+			if err != nil {
+				panic(fmt.Errorf("%s is not a valid department: %v", arg, err))
+			}
+			depts = append(depts, deptId)
+		}
+		if len(depts) == 0 {
+			if depts, err = sqlite.Departments(); err != nil {
+				panic(err)
+			}
+		}
+		var schedules []scrape.DeptSchedule
+		for _, deptId := range depts {
+			rows, err := sqlite.LoadDepartments(deptId, []scrape.Term{term})
+			if err != nil {
+				panic(err)
+			}
+			schedules = append(schedules, rows...)
+		}
+		_ = sqlite.Close()
+		if len(roomBuildings) > 0 {
+			schedules = inBuildings(schedules, roomBuildings)
+		}
+
+		rooms := report.AnalyzeRooms(schedules, opts)
+		if len(rooms.Rooms) == 0 {
+			log.Fatalln("No rooms found in", term, "- run `isqool sync` first")
+		}
+		if err := report.WriteRoomTable(os.Stdout, rooms.Rooms); err != nil {
+			panic(err)
+		}
+		if len(rooms.DoubleBookings) > 0 {
+			fmt.Println()
+			if err := report.WriteDoubleBookingTable(os.Stdout, rooms.DoubleBookings); err != nil {
+				panic(err)
+			}
+		}
+		if roomIdle {
+			fmt.Println()
+			if err := report.WriteIdleTable(os.Stdout, rooms.IdleBlocks); err != nil {
+				panic(err)
+			}
+		}
+
+		// Write the occupancy and grids to files
+		files := 0
+		if roomOccupancy {
+			files++
+		}
+		if roomGrid {
+			files += len(rooms.Buildings())
+		}
+		if output != "" && files > 1 {
+			log.Fatalln("--output can only be used when writing one file")
+		}
+		termId, _ := term.Id()
+		if roomOccupancy {
+			format, fileName := parseOutputFlags(fmt.Sprintf("%d_occupancy", termId))
+			if err := report.WriteFormat(rooms.Occupancy, format, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+		if roomGrid {
+			for _, building := range rooms.Buildings() {
+				format, fileName := parseOutputFlags(fmt.Sprintf("%d_%s_grid", termId, building))
+				if err := report.WriteFormat(rooms.Grid(building), format, fileName); err != nil {
+					panic(err)
+				}
+				log.Println("Wrote to file", fileName)
+			}
+		}
+	},
+}
+
+// inBuildings keeps the meetings of sections held in the given buildings
+func inBuildings(schedules []scrape.DeptSchedule, buildings []string) []scrape.DeptSchedule {
+	keep := make(map[string]bool)
+	for _, building := range buildings {
+		keep[strings.ToUpper(building)] = true
+	}
+	var filtered []scrape.DeptSchedule
+	for _, section := range schedules {
+		var meetings []scrape.Meeting
+		for _, m := range section.Meetings {
+			if m.Building.Valid && keep[strings.ToUpper(m.Building.StringVal)] {
+				meetings = append(meetings, m)
+			}
+		}
+		if len(meetings) > 0 {
+			section.Meetings = meetings
+			filtered = append(filtered, section)
+		}
+	}
+	return filtered
+}
+
+// parseClock parses a time of day like "08:00" into minutes after midnight
+func parseClock(s string) int {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		panic(fmt.Errorf("%s is not a valid time of day (e.g. 08:00): %v", s, err))
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+func init() {
+	rootCmd.AddCommand(roomsCmd)
+
+	addOutputFlags(roomsCmd)
+	roomsCmd.Flags().StringSliceVar(&roomBuildings, "building", nil, "Only analyze the rooms in these buildings")
+	roomsCmd.Flags().StringVar(&roomDays, "days", "MTWRF", "Days rooms are open, as Banner abbreviates them")
+	roomsCmd.Flags().StringVar(&roomOpen, "open", "08:00", "Time of day rooms open")
+	roomsCmd.Flags().StringVar(&roomClose, "close", "22:00", "Time of day rooms close")
+	roomsCmd.Flags().DurationVar(&roomSlot, "slot", 30*time.Minute, "Length of the time slots of the occupancy and grid")
+	roomsCmd.Flags().DurationVar(&roomMinIdle, "min-idle", time.Hour, "How long a room must be free to count as idle")
+	roomsCmd.Flags().BoolVar(&roomIdle, "idle", false, "Also list the blocks of time rooms are idle (default: false)")
+	roomsCmd.Flags().BoolVar(&roomOccupancy, "occupancy", false, "Write how many rooms are in use in each time slot (default: false)")
+	roomsCmd.Flags().BoolVar(&roomGrid, "grid", false, "Write a weekly grid of the rooms in use for each building (default: false)")
+}
@@ -3,14 +3,26 @@ package cmd
 import (
 	"fmt"
 	"github.com/gocolly/colly/v2"
+	"github.com/openswoop/isqool/pkg/config"
+	"github.com/openswoop/isqool/pkg/database"
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/webcache"
 	"github.com/spf13/cobra"
+	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 var c *colly.Collector
 
-var cacheDir = "/isqool/web-cache"
+var cfg config.Config
+var settings []config.Setting
+var configFile string
+
 var noCache bool
+var cacheTtls map[string]string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -31,18 +43,153 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initColly)
+	cobra.OnInitialize(initConfig, initColly)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", config.DefaultFile(), "Config file (env: ISQOOL_CONFIG)")
+	config.AddFlags(rootCmd.PersistentFlags())
 	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Bypass the web cache (default: false)")
+	rootCmd.PersistentFlags().StringToStringVar(&cacheTtls, "cache-ttl", nil, "How long to cache each class of page (default: isq=168h,schedule=24h,department=6h,catalog=168h,seats=0s,other=24h)")
+}
+
+// initConfig layers the config file, environment, and flags into cfg
+func initConfig() {
+	file, explicit := configFile, rootCmd.Flags().Changed("config")
+	if env, found := os.LookupEnv("ISQOOL_CONFIG"); found && !explicit {
+		file, explicit = env, true
+	}
+
+	var err error
+	cfg, settings, err = config.Load(file, explicit, rootCmd.PersistentFlags())
+	if err != nil {
+		panic(fmt.Errorf("failed to load config: %v", err))
+	}
+	configFile = file
+
+	institution, err := scrape.LoadInstitution(cfg.Institution)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.BannerUrl != "" {
+		institution.BannerUrl = cfg.BannerUrl
+	}
+	scrape.Current = institution
 }
 
 func initColly() {
 	c = colly.NewCollector()
+	if cfg.UserAgent != "" {
+		c.UserAgent = cfg.UserAgent
+	}
+	if err := c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: cfg.Concurrency}); err != nil {
+		panic(err)
+	}
 	if !noCache {
-		userCacheDir, _ := os.UserCacheDir()
-		c.CacheDir = userCacheDir + cacheDir
+		c.WithTransport(webCache().Transport(http.DefaultTransport))
+	}
+}
+
+// defaultCacheTtls are how long each class of page is cached. ISQs only
+// change once a term, while schedules and waitlists change daily. The
+// catalog rarely changes within a term, and seats are snapshotted so they're
+// always fetched fresh.
+var defaultCacheTtls = map[string]time.Duration{
+	scrape.IsqPage:        7 * 24 * time.Hour,
+	scrape.SchedulePage:   24 * time.Hour,
+	scrape.DepartmentPage: 6 * time.Hour,
+	scrape.CatalogPage:    7 * 24 * time.Hour,
+	scrape.SeatPage:       0,
+	scrape.OtherPage:      24 * time.Hour,
+}
+
+// cacheTtl returns how long each class of page is cached, with the
+// lifetimes given by --cache-ttl
+func cacheTtl() map[string]time.Duration {
+	ttls := make(map[string]time.Duration)
+	for class, ttl := range defaultCacheTtls {
+		ttls[class] = ttl
+	}
+	for class, value := range cacheTtls {
+		if _, found := ttls[class]; !found {
+			panic(fmt.Errorf("unknown page class in --cache-ttl: %s", class))
+		}
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			panic(fmt.Errorf("invalid --cache-ttl for %s: %v", class, err))
+		}
+		ttls[class] = ttl
+	}
+	return ttls
+}
+
+// webCache returns the web cache
+func webCache() webcache.Cache {
+	ttls := cacheTtl()
+	return webcache.Cache{
+		Dir: cfg.CacheDir,
+		TTL: func(url string) time.Duration {
+			return ttls[scrape.PageClass(url)]
+		},
+	}
+}
+
+// openSqlite opens the local SQLite database, creating its directory if needed
+func openSqlite() database.Sqlite {
+	if err := os.MkdirAll(filepath.Dir(cfg.Database), 0755); err != nil {
+		panic(err)
+	}
+	return database.NewSqlite(cfg.Database)
+}
+
+// latestTerm is the most recent term with data in the local database, which
+// stands in for the current term when none is given
+func latestTerm(sqlite database.Sqlite) (scrape.Term, error) {
+	terms, err := sqlite.Terms()
+	if err != nil {
+		return "", err
+	}
+	var latest scrape.Term
+	for _, term := range terms {
+		if term.Valid() && (latest == "" || latest.Before(term)) {
+			latest = term
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("the local database has no terms yet, give a --term")
+	}
+	return latest, nil
+}
+
+var outputFormat string
+var output string
+
+// addOutputFlags adds the flags selecting how a command writes its report
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "csv", "Output format: "+report.FormatNames())
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file, or - for stdout (default: <name>.<format>)")
+}
+
+// parseOutputFlags returns the format and file name of a report named name
+func parseOutputFlags(name string) (report.Format, string) {
+	format, err := report.ParseFormat(outputFormat)
+	if err != nil {
+		panic(err)
+	}
+	if output != "" {
+		return format, report.OutputName(name, format, output)
+	}
+	return format, outputPath(report.OutputName(name, format, output))
+}
+
+// outputPath places a report's default file name in the output directory
+func outputPath(fileName string) string {
+	if cfg.OutputDir == "" {
+		return fileName
+	}
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		panic(err)
 	}
+	return filepath.Join(cfg.OutputDir, fileName)
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var seatsCourse string
+var seatsCrn int
+
+// seatsCmd represents the seats command
+var seatsCmd = &cobra.Command{
+	Use:   "seats [department] [term]",
+	Short: "Show how a department's sections filled up",
+	Long: `Given a department ID and a term (such as "Fall 2023") this command
+will show how each section filled up during registration: its latest
+capacity, enrollment, and wait list, and a curve of how full it was in each
+snapshot.
+
+Snapshots are taken from the local database, which sync adds one to each
+time it runs, so the curves are only as fine as the sync schedule. Use
+--format or --output to also write every snapshot to a file.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		deptId, err := strconv.Atoi(args[0]) // e.g. 6502
+		if err != nil {
+			panic(fmt.Errorf("%s is not a valid department: %v", args[0], err))
+		}
+		term, err := scrape.ParseTerm(args[1])
+		if err != nil {
+			panic(err)
+		}
+
+		sqlite := openSqlite()
+		snapshots, err := sqlite.LoadSnapshots(deptId, term)
+		_ = sqlite.Close()
+		if err != nil {
+			panic(err)
+		}
+		var filtered []scrape.SectionSnapshot
+		for _, s := range snapshots {
+			if (seatsCourse == "" || s.Name == seatsCourse) && (seatsCrn == 0 || s.Crn == seatsCrn) {
+				filtered = append(filtered, s)
+			}
+		}
+		if len(filtered) == 0 {
+			log.Fatalln("No seat snapshots found for department", deptId, "in", term, "- run `isqool sync` first")
+		}
+
+		curves := report.BuildSeatCurves(filtered)
+		if output != "-" {
+			if err := report.WriteSeatTable(os.Stdout, curves); err != nil {
+				panic(err)
+			}
+		}
+
+		// Write every snapshot to a file if asked to
+		if output != "" || cmd.Flags().Changed("format") {
+			termId, _ := term.Id()
+			format, fileName := parseOutputFlags(fmt.Sprintf("%d_%d_seats", deptId, termId))
+			if err := report.WriteFormat(report.SeatPoints(curves), format, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(seatsCmd)
+
+	addOutputFlags(seatsCmd)
+
+	seatsCmd.Flags().StringVar(&seatsCourse, "course", "", "Only show the sections of this course (e.g. COP2220)")
+	seatsCmd.Flags().IntVar(&seatsCrn, "crn", 0, "Only show the section with this CRN")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/server"
+
+	"github.com/spf13/cobra"
+)
+
+var addr string
+var maxAge time.Duration
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the course data over a JSON API",
+	Long: `Serves the data in the local database over a REST API. Courses,
+instructors, and departments that haven't been fetched yet are scraped on
+demand and saved. The OpenAPI document is served at /openapi.json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sqlite := openSqlite()
+		defer sqlite.Close()
+
+		log.Println("Listening on", addr)
+		log.Fatal(http.ListenAndServe(addr, server.New(sqlite, c, maxAge)))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&addr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().DurationVar(&maxAge, "max-age", time.Hour, "How long clients may cache responses")
+}
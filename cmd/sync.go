@@ -10,128 +10,488 @@ import (
 	"github.com/openswoop/isqool/pkg/scrape"
 	"log"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// Pages are fingerprinted by kind, so courses and department terms can't
+// collide. History inserts are fingerprinted too, to know when the last one
+// was.
 const (
-	projectID = "syllabank-4e5b9"
-	datasetID = "isqool"
-	topicID   = "department-refreshed"
+	departmentPage = "department"
+	coursePage     = "course"
+	catalogPage    = "catalog"
+	historyInsert  = "history"
 )
 
+// recentTerms is how far back from the seed term, in term ids, a term is
+// still fetched on every sync: a year, since grades come in late
+const recentTerms = 100
+
 var dryRun bool
 var debug bool
+var full bool
+var resume bool
 
 // syncCmd represents the sync command
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Scrape departmental data to BigQuery",
 	Long: `This command takes a department ID and term (such as "Spring 2020")
-and scrapes the ISQs, grades, and schedules of the courses offered.`,
+and scrapes the ISQs, grades, schedules, and catalog entries of the
+courses offered, along with a snapshot of each section's seats. Changes to
+the department's schedule are kept as a history of each section (see
+isqool changes). Pages that haven't changed since the last sync are
+skipped, as are terms more than a year before the given term once they've
+been synced; pass --full to re-fetch and save everything. Each course and
+term scraped is checkpointed, so a sync that fails part way can be
+continued with --resume.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		deptId, _ := strconv.Atoi(args[0]) // e.g. 6502
-		seedTerm := args[1]                // e.g. Spring 2020
-
-		// Scrape the first term as a starting point
-		initialDept, err := scrape.GetDepartment(c, seedTerm, deptId)
+		deptId, _ := strconv.Atoi(args[0])         // e.g. 6502
+		seedTerm, err := scrape.ParseTerm(args[1]) // e.g. Spring 2020
 		if err != nil {
 			panic(err)
 		}
 
 		// If the debug flag is set, output the CSV and exit early
 		if debug {
-			err := report.WriteDepartment(fmt.Sprintf("%d_%s", deptId, seedTerm), initialDept)
+			initialDept, err := scrape.GetDepartment(c, seedTerm, deptId)
 			if err != nil {
 				panic(err)
 			}
+			format, fileName := parseOutputFlags(fmt.Sprintf("%d_%s", deptId, seedTerm))
+			if err := report.WriteDepartment(fileName, format, initialDept); err != nil {
+				panic(err)
+			}
 			return
 		}
 
-		seen := make(map[string]bool)
-		var courses []string
-		for _, row := range initialDept {
-			if _, found := seen[row.Name]; !found {
-				courses = append(courses, row.Name)
-				seen[row.Name] = true
+		if err := syncDepartment(deptId, seedTerm); err != nil {
+			panic(err)
+		}
+		fmt.Println("Done.")
+	},
+}
+
+// syncDepartment scrapes a department's schedule in seedTerm, the ISQs,
+// grades, and catalog entries of its courses, and its schedules in the terms
+// those courses were offered, then saves them locally and to BigQuery. Unless
+// full is set, only pages that changed since the last sync are saved, and old
+// terms that were already synced aren't fetched again.
+func syncDepartment(deptId int, seedTerm scrape.Term) error {
+	seedId, err := seedTerm.Id()
+	if err != nil {
+		return err
+	}
+
+	sqlite := openSqlite()
+	defer sqlite.Close()
+
+	// changed reports whether the rows scraped from a page differ from the
+	// last sync, collecting the page's new fingerprint if so
+	var fingerprints []database.Fingerprint
+	changed := func(kind, key string, rows interface{}) (bool, error) {
+		fingerprint, err := database.NewFingerprint(kind, key, rows)
+		if err != nil {
+			return false, err
+		}
+		last, found, err := sqlite.Fingerprint(kind, key)
+		if err != nil {
+			return false, err
+		}
+		if found && last.Hash == fingerprint.Hash && !full {
+			return false, nil
+		}
+		fingerprints = append(fingerprints, fingerprint)
+		return true, nil
+	}
+
+	// checkpoint scrapes a unit of work into rows, saving them so a failed
+	// sync can resume without scraping the unit again. Dry runs don't save
+	// checkpoints, since they don't modify the database.
+	syncKey := deptKey(deptId, seedTerm)
+	if resume {
+		if n, err := sqlite.CountCheckpoints(syncKey); err == nil && n > 0 {
+			log.Printf("Resuming sync of department %d from %d checkpoints", deptId, n)
+		}
+	} else if !dryRun {
+		if err := sqlite.ClearCheckpoints(syncKey); err != nil {
+			return err
+		}
+	}
+	checkpoint := func(unit string, rows interface{}, fetch func() error) error {
+		if resume {
+			found, err := sqlite.LoadCheckpoint(syncKey, unit, rows)
+			if err != nil || found {
+				return err
 			}
 		}
+		if err := fetch(); err != nil || dryRun {
+			return err
+		}
+		return sqlite.SaveCheckpoint(syncKey, unit, rows)
+	}
+
+	// Scrape the first term as a starting point
+	var initialDept []scrape.DeptSchedule
+	err = checkpoint(departmentPage+"/"+string(seedTerm), &initialDept, func() (err error) {
+		initialDept, err = scrape.GetDepartment(c.Clone(), seedTerm, deptId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := changed(departmentPage, deptKey(deptId, seedTerm), initialDept); err != nil {
+		return err
+	}
 
-		// Scrape all the courses offered that term
-		var isqTable []scrape.CourseIsq
-		var gradesTable []scrape.CourseGrades
-		for _, course := range courses {
-			isqs, grades, err := scrape.GetIsqAndGrades(c.Clone(), course, false)
+	seen := make(map[string]bool)
+	var courses []string
+	for _, row := range initialDept {
+		if _, found := seen[row.Name]; !found {
+			courses = append(courses, row.Name)
+			seen[row.Name] = true
+		}
+	}
+
+	// Scrape the ISQs and grades of all the courses offered that term,
+	// keeping the ones that changed, if the institution has them
+	var allIsqs []scrape.CourseIsq
+	var isqTable []scrape.CourseIsq
+	var gradesTable []scrape.CourseGrades
+	if scrape.Current.Has(scrape.IsqPage) {
+		pages := make([]courseRows, len(courses))
+		err = parallel(len(courses), func(i int) error {
+			return checkpoint(coursePage+"/"+courses[i], &pages[i], func() (err error) {
+				pages[i].Isqs, pages[i].Grades, err = scrape.GetIsqAndGrades(c.Clone(), courses[i], false)
+				return err
+			})
+		})
+		if err != nil {
+			return err
+		}
+		for i, rows := range pages {
+			allIsqs = append(allIsqs, rows.Isqs...)
+			ok, err := changed(coursePage, courses[i], rows)
 			if err != nil {
-				panic(err)
+				return err
+			}
+			if ok {
+				isqTable = append(isqTable, rows.Isqs...)
+				gradesTable = append(gradesTable, rows.Grades...)
 			}
-			isqTable = append(isqTable, isqs...)
-			gradesTable = append(gradesTable, grades...)
 		}
+	}
 
-		seen = make(map[string]bool)
-		var terms []string
-		for _, row := range isqTable {
-			if _, found := seen[row.Term]; !found && row.Term != seedTerm {
-				terms = append(terms, row.Term)
-				seen[row.Term] = true
+	// Scrape the catalog entries of those courses, if the institution has a
+	// catalog
+	var catalogTable []scrape.CatalogEntry
+	if scrape.Current.Has(scrape.CatalogPage) {
+		entries := make([]scrape.CatalogEntry, len(courses))
+		err = parallel(len(courses), func(i int) error {
+			return checkpoint(catalogPage+"/"+courses[i], &entries[i], func() (err error) {
+				entries[i], err = scrape.GetCatalogEntry(c.Clone(), courses[i], seedTerm)
+				if err == scrape.ErrNotInCatalog {
+					return nil
+				}
+				return err
+			})
+		})
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Title == "" {
+				continue // not in the catalog
 			}
+			// The same entry is read from each seed term's catalog
+			fingerprinted := entry
+			fingerprinted.Term = ""
+			ok, err := changed(catalogPage, entry.Name, fingerprinted)
+			if err != nil {
+				return err
+			}
+			if ok {
+				catalogTable = append(catalogTable, entry)
+			}
+		}
+	}
+
+	seenTerms := make(map[scrape.Term]bool)
+	var terms []scrape.Term
+	for _, row := range allIsqs {
+		if _, found := seenTerms[row.Term]; !found && row.Term != seedTerm {
+			terms = append(terms, row.Term)
+			seenTerms[row.Term] = true
 		}
+	}
 
-		// Scrape all the terms those courses were offered in
-		deptTable := initialDept
-		for _, term := range terms {
-			dept, err := scrape.GetDepartment(c, term, deptId)
+	// Scrape all the terms those courses were offered in, as far as the ISQs
+	// tell. Old terms don't change, so they're skipped once synced.
+	var fetchTerms []scrape.Term
+	for _, term := range terms {
+		if termId, _ := term.Id(); !full && termId < seedId-recentTerms {
+			_, found, err := sqlite.Fingerprint(departmentPage, deptKey(deptId, term))
 			if err != nil {
-				panic(err)
+				return err
+			}
+			if found {
+				continue
 			}
+		}
+		fetchTerms = append(fetchTerms, term)
+	}
+	depts := make([][]scrape.DeptSchedule, len(fetchTerms))
+	err = parallel(len(fetchTerms), func(i int) error {
+		return checkpoint(departmentPage+"/"+string(fetchTerms[i]), &depts[i], func() (err error) {
+			depts[i], err = scrape.GetDepartment(c.Clone(), fetchTerms[i], deptId)
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+	deptTable := initialDept
+	for i, dept := range depts {
+		ok, err := changed(departmentPage, deptKey(deptId, fetchTerms[i]), dept)
+		if err != nil {
+			return err
+		}
+		if ok {
 			deptTable = append(deptTable, dept...)
 		}
+	}
 
-		// Connect to BigQuery
-		bq, err := database.NewBigQuery(projectID, datasetID)
-		if err != nil {
-			panic(fmt.Errorf("failed to connect to bigquery: %v", err))
+	// Connect to BigQuery
+	bq, err := database.NewBigQuery(cfg.Project, cfg.Dataset)
+	if err != nil {
+		return fmt.Errorf("failed to connect to bigquery: %v", err)
+	}
+	defer bq.Close()
+
+	// Snapshot the seats of every section this term, even if nothing else
+	// changed, to see how they fill up during registration
+	if scrape.Current.Has(scrape.SeatPage) {
+		if err := snapshotSeats(sqlite, bq, deptId, initialDept); err != nil {
+			return err
 		}
+	}
 
-		// Insert (merge) the department schedules, isqs, and grades
-		if !dryRun {
-			if err := bq.InsertDepartments(deptTable, deptId, seedTerm); err != nil {
-				panic(fmt.Errorf("failed to insert department schedule: %v", err))
-			}
+	if len(fingerprints) == 0 {
+		log.Printf("Department %d hasn't changed since the last sync", deptId)
+		if dryRun {
+			return nil
+		}
+		return sqlite.ClearCheckpoints(syncKey)
+	}
+
+	if !dryRun {
+		// Save everything to the local database so reports can be made offline
+		if err := sqlite.SaveDepartments(deptTable); err != nil {
+			return fmt.Errorf("failed to save department schedule: %v", err)
+		}
+		savedAt := time.Now().UTC()
+		if err := sqlite.SaveHistory(deptTable, savedAt); err != nil {
+			return fmt.Errorf("failed to save department history: %v", err)
+		}
+		if err := sqlite.SaveIsqs(isqTable); err != nil {
+			return fmt.Errorf("failed to save isqs: %v", err)
+		}
+		if err := sqlite.SaveGrades(gradesTable); err != nil {
+			return fmt.Errorf("failed to save grades: %v", err)
+		}
+		if err := sqlite.SaveCatalog(catalogTable); err != nil {
+			return fmt.Errorf("failed to save catalog: %v", err)
+		}
+
+		// Insert (merge) the department schedules, isqs, grades, and catalog
+		if err := bq.InsertDepartments(deptTable, deptId, seedTerm); err != nil {
+			return fmt.Errorf("failed to insert department schedule: %v", err)
+		}
+		inserted, err := insertHistory(sqlite, bq, syncKey, deptId, deptTable, savedAt)
+		if err != nil {
+			return err
+		}
+		fingerprints = append(fingerprints, inserted)
+		if len(isqTable) > 0 {
 			if err := bq.InsertISQs(isqTable); err != nil {
-				panic(fmt.Errorf("failed to insert isqs: %v", err))
+				return fmt.Errorf("failed to insert isqs: %v", err)
 			}
+		}
+		if len(gradesTable) > 0 {
 			if err := bq.InsertGrades(gradesTable); err != nil {
-				panic(fmt.Errorf("failed to insert grades: %v", err))
+				return fmt.Errorf("failed to insert grades: %v", err)
+			}
+		}
+		if len(catalogTable) > 0 {
+			if err := bq.InsertCatalog(catalogTable); err != nil {
+				return fmt.Errorf("failed to insert catalog: %v", err)
 			}
-		} else {
-			fmt.Println("Dry run: data will not be inserted")
 		}
 
-		// Connect to PubSub
-		ctx := context.Background()
-		client, err := pubsub.NewClient(ctx, projectID)
-		if err != nil {
-			log.Fatalf("Failed to create client: %v", err)
+		// Only remember what was synced once it's safely in BigQuery
+		if err := sqlite.SaveFingerprints(fingerprints); err != nil {
+			return fmt.Errorf("failed to save fingerprints: %v", err)
+		}
+		if err := sqlite.ClearCheckpoints(syncKey); err != nil {
+			return fmt.Errorf("failed to clear checkpoints: %v", err)
 		}
+	} else {
+		fmt.Println("Dry run: data will not be saved or inserted")
+	}
 
-		msg, err := json.Marshal(struct {
-			DepartmentId int `json:"departmentId"`
-		}{deptId})
+	// Connect to PubSub
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, cfg.Project)
+	if err != nil {
+		return fmt.Errorf("failed to create pubsub client: %v", err)
+	}
+	defer client.Close()
+
+	msg, err := json.Marshal(struct {
+		DepartmentId int `json:"departmentId"`
+	}{deptId})
+	if err != nil {
+		return fmt.Errorf("failed to create message: %v", err)
+	}
+
+	// Publish an event
+	topic := client.Topic(cfg.Topic)
+	defer topic.Stop()
+	res := topic.Publish(ctx, &pubsub.Message{Data: msg})
+	if _, err := res.Get(ctx); err != nil {
+		return fmt.Errorf("failed to publish message: %v", err)
+	}
+
+	return nil
+}
+
+// insertHistory sends the versions of the sections in a department's
+// schedule that were opened or closed since the history was last inserted,
+// so versions closed by an earlier sync that failed to insert them are caught
+// up. It returns the fingerprint recording this insert.
+func insertHistory(sqlite database.Sqlite, bq database.BigQuery, key string, deptId int,
+	deptTable []scrape.DeptSchedule, savedAt time.Time) (database.Fingerprint, error) {
+	var terms []scrape.Term
+	seen := make(map[scrape.Term]bool)
+	for _, row := range deptTable {
+		if !seen[row.Term] {
+			terms = append(terms, row.Term)
+			seen[row.Term] = true
+		}
+	}
+	versions, err := sqlite.LoadHistory(deptId, terms)
+	if err != nil {
+		return database.Fingerprint{}, fmt.Errorf("failed to load department history: %v", err)
+	}
+	var since time.Time
+	last, found, err := sqlite.Fingerprint(historyInsert, key)
+	if err != nil {
+		return database.Fingerprint{}, err
+	}
+	if found && !full {
+		since = time.Unix(last.SyncedAt, 0)
+	}
+	var changed []scrape.ScheduleVersion
+	for _, v := range versions {
+		if !v.ValidFrom.Before(since) || (v.ValidTo.Valid && !v.ValidTo.Timestamp.Before(since)) {
+			changed = append(changed, v)
+		}
+	}
+	fingerprint, err := database.NewFingerprint(historyInsert, key, changed)
+	if err != nil {
+		return database.Fingerprint{}, err
+	}
+	fingerprint.SyncedAt = savedAt.Unix()
+	if len(changed) == 0 {
+		return fingerprint, nil
+	}
+	if err := bq.InsertHistory(changed); err != nil {
+		return database.Fingerprint{}, fmt.Errorf("failed to insert department history: %v", err)
+	}
+	return fingerprint, nil
+}
+
+// snapshotSeats scrapes the seats of a department's sections and saves them
+// with the time they were taken. Sections whose seats can't be scraped, like
+// cancelled ones, are skipped rather than failing the sync.
+func snapshotSeats(sqlite database.Sqlite, bq database.BigQuery, deptId int, sections []scrape.DeptSchedule) error {
+	takenAt := time.Now().UTC()
+	scraped := make([]scrape.SectionSnapshot, len(sections))
+	errs := parallelEach(len(sections), func(i int) (err error) {
+		scraped[i] = scrape.SectionSnapshot{Course: sections[i].Course, Department: deptId, TakenAt: takenAt}
+		scraped[i].Seats, err = scrape.GetSeats(c.Clone(), sections[i].Term, sections[i].Crn)
+		return err
+	})
+	var snapshots []scrape.SectionSnapshot
+	for i, err := range errs {
 		if err != nil {
-			log.Fatalf("Failed to create message: %v", err)
+			log.Printf("Warning: skipping the seats of %s %d: %v", sections[i].Name, sections[i].Crn, err)
+			continue
+		}
+		snapshots = append(snapshots, scraped[i])
+	}
+	if dryRun {
+		return nil
+	}
+	if err := sqlite.SaveSnapshots(snapshots); err != nil {
+		return fmt.Errorf("failed to save seat snapshots: %v", err)
+	}
+	if len(snapshots) > 0 {
+		if err := bq.InsertSnapshots(snapshots); err != nil {
+			return fmt.Errorf("failed to insert seat snapshots: %v", err)
 		}
+	}
+	return nil
+}
 
-		// Publish an event
-		topic := client.Topic(topicID)
-		res := topic.Publish(ctx, &pubsub.Message{Data: msg})
-		if _, err := res.Get(ctx); err != nil {
-			log.Fatalf("Failed to publish message: %v", err)
+// parallel calls fn for each index below n, running at most cfg.Concurrency
+// at once, and returns the first error
+func parallel(n int, fn func(i int) error) error {
+	for _, err := range parallelEach(n, fn) {
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		fmt.Println("Done.")
-	},
+// parallelEach is like parallel, but returns the error of every call
+func parallelEach(n int, fn func(i int) error) []error {
+	slots := make(chan struct{}, cfg.Concurrency)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			// Scraping panics on unexpected pages, which would otherwise
+			// take down the whole process from a goroutine
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+// courseRows are the rows scraped from a course's page
+type courseRows struct {
+	Isqs   []scrape.CourseIsq
+	Grades []scrape.CourseGrades
+}
+
+// deptKey identifies a department's page in a term
+func deptKey(deptId int, term scrape.Term) string {
+	return fmt.Sprintf("%d/%s", deptId, term)
 }
 
 func init() {
@@ -144,4 +504,7 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly:
 	syncCmd.Flags().BoolVar(&debug, "debug", false, "Dump the departmental summary as a CSV (default: false)")
+	syncCmd.Flags().BoolVar(&full, "full", false, "Re-fetch and save every term, even if unchanged (default: false)")
+	syncCmd.Flags().BoolVar(&resume, "resume", false, "Continue a failed sync from its last checkpoint (default: false)")
+	addOutputFlags(syncCmd)
 }
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+
+	"github.com/spf13/cobra"
+)
+
+var trendWindow int
+var trendInstructor string
+var trendCsv bool
+var trendJson bool
+
+// trendCmd represents the trend command
+var trendCmd = &cobra.Command{
+	Use:   "trend [course|professor]",
+	Short: "Show how a course or professor has changed over time",
+	Long: `Given a course name or professor's N# this command will output a
+per-term time series of the enrollment, rating, average GPA, and response
+rate, along with rolling averages. A least squares slope is fit to each
+metric, and metrics whose slope is significantly different from zero, by
+a t-test at the 95% level, are flagged as drifting. At least 4 terms are
+needed to test a slope.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0] // COT3100 or N00474503 etc.
+		isProfessor, _ := regexp.MatchString("N\\d{8}", name)
+
+		isqs, grades, err := scrape.GetIsqAndGrades(c.Clone(), name, isProfessor)
+		if err != nil {
+			panic(err)
+		}
+
+		input := report.CourseInput{
+			Isqs:   isqs,
+			Grades: grades,
+		}
+		// Only keep the sections taught by one instructor, if requested
+		if trendInstructor != "" {
+			input = report.ByInstructor(input, trendInstructor)
+		}
+		trend := report.Trend(input, trendWindow)
+
+		if err := report.WriteTrendTable(os.Stdout, trend); err != nil {
+			panic(err)
+		}
+		if trendCsv {
+			fileName := outputPath(name + "_trend.csv")
+			if err := report.WriteCsv(trend.Points, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+		if trendJson {
+			fileName := outputPath(name + "_trend.json")
+			if err := report.WriteJson(trend, fileName); err != nil {
+				panic(err)
+			}
+			log.Println("Wrote to file", fileName)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trendCmd)
+
+	trendCmd.Flags().IntVar(&trendWindow, "window", 3, "Number of terms in each rolling average")
+	trendCmd.Flags().StringVar(&trendInstructor, "instructor", "", "Only include sections taught by this instructor (last name)")
+	trendCmd.Flags().BoolVar(&trendCsv, "csv", false, "Also write the time series to a CSV file (default: false)")
+	trendCmd.Flags().BoolVar(&trendJson, "json", false, "Also write the time series and slopes to a JSON file (default: false)")
+}
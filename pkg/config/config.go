@@ -0,0 +1,184 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Config holds the settings shared by every command. Each setting can be
+// given in the config file by its key, in the environment as ISQOOL_<KEY>,
+// or as the flag --<key> (with dashes for underscores), in increasing order
+// of precedence.
+type Config struct {
+	Database    string `json:"database" help:"SQLite database file"`
+	CacheDir    string `json:"cache_dir" help:"Directory of the web cache"`
+	Institution string `json:"institution" help:"Institution to scrape: a built-in profile (unf) or a JSON profile file"`
+	BannerUrl   string `json:"banner_url" help:"Base URL of Banner Self-Service, overriding the institution's"`
+	OutputDir   string `json:"output_dir" help:"Directory reports are written to, unless --output is given"`
+	Concurrency int    `json:"concurrency" help:"How many pages to fetch at once"`
+	UserAgent   string `json:"user_agent" help:"User agent sent to Banner"`
+	Project     string `json:"project" help:"Google Cloud project to sync to"`
+	Dataset     string `json:"dataset" help:"BigQuery dataset to sync to"`
+	Topic       string `json:"topic" help:"Pub/Sub topic notified after a sync"`
+}
+
+// Sources of a setting's value
+const (
+	FromDefault = "default"
+	FromFile    = "file"
+	FromEnv     = "env"
+	FromFlag    = "flag"
+)
+
+// Setting is a setting's key, value, and where the value came from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Default returns the settings used when nothing else is given
+func Default() Config {
+	userCacheDir, _ := os.UserCacheDir()
+	return Config{
+		Database:    filepath.Join(userCacheDir, "isqool", "isqool.db"),
+		CacheDir:    filepath.Join(userCacheDir, "isqool", "web-cache"),
+		Institution: "unf",
+		Concurrency: 1,
+		Project:     "syllabank-4e5b9",
+		Dataset:     "isqool",
+		Topic:       "department-refreshed",
+	}
+}
+
+// DefaultFile is where the config file is looked for if none is given
+func DefaultFile() string {
+	userConfigDir, _ := os.UserConfigDir()
+	return filepath.Join(userConfigDir, "isqool", "config.json")
+}
+
+// AddFlags adds a flag for every setting to flags
+func AddFlags(flags *pflag.FlagSet) {
+	defaults := reflect.ValueOf(Default())
+	t := defaults.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := flagName(field)
+		usage := field.Tag.Get("help")
+		switch field.Type.Kind() {
+		case reflect.Int:
+			flags.Int(name, int(defaults.Field(i).Int()), usage)
+		default:
+			flags.String(name, defaults.Field(i).String(), usage)
+		}
+	}
+}
+
+// Load layers the config file, the environment, and the flags that were set
+// over the defaults. The file may be missing unless it was given explicitly.
+func Load(file string, explicit bool, flags *pflag.FlagSet) (Config, []Setting, error) {
+	config := Default()
+	sources := make(map[string]string)
+
+	b, err := os.ReadFile(file)
+	if err == nil {
+		var fromFile map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fromFile); err != nil {
+			return config, nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		if err := json.Unmarshal(b, &config); err != nil {
+			return config, nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		for key := range fromFile {
+			if !isKnown(key) {
+				return config, nil, fmt.Errorf("unknown setting in %s: %s", file, key)
+			}
+			sources[key] = FromFile
+		}
+	} else if explicit || !os.IsNotExist(err) {
+		return config, nil, err
+	}
+
+	v := reflect.ValueOf(&config).Elem()
+	t := v.Type()
+	var settings []Setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := jsonName(field)
+		if value, found := os.LookupEnv(envName(field)); found {
+			if err := set(v.Field(i), value); err != nil {
+				return config, nil, fmt.Errorf("invalid %s: %v", envName(field), err)
+			}
+			sources[key] = FromEnv
+		}
+		if flags != nil {
+			if flag := flags.Lookup(flagName(field)); flag != nil && flag.Changed {
+				if err := set(v.Field(i), flag.Value.String()); err != nil {
+					return config, nil, fmt.Errorf("invalid --%s: %v", flag.Name, err)
+				}
+				sources[key] = FromFlag
+			}
+		}
+
+		source := sources[key]
+		if source == "" {
+			source = FromDefault
+		}
+		settings = append(settings, Setting{key, fmt.Sprint(v.Field(i).Interface()), source})
+	}
+
+	return config, settings, config.validate()
+}
+
+func (c Config) validate() error {
+	if c.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	if c.BannerUrl != "" && !strings.HasSuffix(c.BannerUrl, "/") {
+		return fmt.Errorf("banner_url must end with a slash")
+	}
+	return nil
+}
+
+func set(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	default:
+		field.SetString(value)
+	}
+	return nil
+}
+
+func isKnown(key string) bool {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonName(field reflect.StructField) string {
+	return field.Tag.Get("json")
+}
+
+func envName(field reflect.StructField) string {
+	return "ISQOOL_" + strings.ToUpper(jsonName(field))
+}
+
+func flagName(field reflect.StructField) string {
+	return strings.ReplaceAll(jsonName(field), "_", "-")
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string // contents of the config file, or none if ""
+		explicit bool
+		env      map[string]string
+		args     []string
+		want     Setting // of the dataset
+		wantErr  bool
+	}{
+		{name: "default", want: Setting{"dataset", "isqool", FromDefault}},
+		{name: "file", file: `{"dataset": "file"}`, want: Setting{"dataset", "file", FromFile}},
+		{
+			name: "env over file",
+			file: `{"dataset": "file"}`,
+			env:  map[string]string{"ISQOOL_DATASET": "env"},
+			want: Setting{"dataset", "env", FromEnv},
+		},
+		{
+			name: "flag over env",
+			file: `{"dataset": "file"}`,
+			env:  map[string]string{"ISQOOL_DATASET": "env"},
+			args: []string{"--dataset=flag"},
+			want: Setting{"dataset", "flag", FromFlag},
+		},
+		{
+			name: "flag over file",
+			file: `{"dataset": "file"}`,
+			args: []string{"--dataset", "flag"},
+			want: Setting{"dataset", "flag", FromFlag},
+		},
+		{name: "missing explicit file", explicit: true, wantErr: true},
+		{name: "unknown setting", file: `{"datset": "file"}`, wantErr: true},
+		{name: "invalid env", env: map[string]string{"ISQOOL_CONCURRENCY": "many"}, wantErr: true},
+		{name: "invalid value", args: []string{"--concurrency=0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.json")
+			if tt.file != "" {
+				if err := os.WriteFile(file, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			flags := pflag.NewFlagSet("isqool", pflag.ContinueOnError)
+			AddFlags(flags)
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			config, settings, err := Load(file, tt.explicit, flags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.Dataset != tt.want.Value {
+				t.Errorf("Dataset = %q, want %q", config.Dataset, tt.want.Value)
+			}
+			for _, s := range settings {
+				if s.Key == tt.want.Key && s != tt.want {
+					t.Errorf("setting = %+v, want %+v", s, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/robfig/cron/v3"
+)
+
+// Config lists the departments the daemon syncs and when
+type Config struct {
+	// MaxConcurrent caps how many syncs may run at once
+	MaxConcurrent int `json:"max_concurrent"`
+	// Jitter is the longest a run may be delayed past its scheduled time
+	Jitter Duration `json:"jitter"`
+	// Addr is the address the health and metrics endpoints listen on
+	Addr        string `json:"addr"`
+	Departments []Job  `json:"departments"`
+}
+
+// Job syncs a department in each of its terms on a cron schedule
+type Job struct {
+	Department int           `json:"department"`
+	Terms      []scrape.Term `json:"terms"`
+	Schedule   string        `json:"schedule"`
+}
+
+// Duration is a time.Duration written as a string such as "5m" in JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	*d = Duration(duration)
+	return err
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads and validates a JSON config file
+func LoadConfig(file string) (Config, error) {
+	config := Config{MaxConcurrent: 1, Addr: ":8081"}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return config, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return config, config.validate()
+}
+
+func (c Config) validate() error {
+	if c.MaxConcurrent < 1 {
+		return fmt.Errorf("max_concurrent must be at least 1")
+	}
+	if len(c.Departments) == 0 {
+		return fmt.Errorf("no departments to sync")
+	}
+	seen := make(map[int]bool)
+	for _, job := range c.Departments {
+		if seen[job.Department] {
+			return fmt.Errorf("department %d is listed more than once", job.Department)
+		}
+		seen[job.Department] = true
+		if len(job.Terms) == 0 {
+			return fmt.Errorf("department %d has no terms", job.Department)
+		}
+		for _, term := range job.Terms {
+			if _, err := term.Id(); err != nil {
+				return fmt.Errorf("department %d: %v", job.Department, err)
+			}
+		}
+		if _, err := cron.ParseStandard(job.Schedule); err != nil {
+			return fmt.Errorf("department %d has an invalid schedule: %v", job.Department, err)
+		}
+	}
+	return nil
+}
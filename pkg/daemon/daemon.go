@@ -0,0 +1,284 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/robfig/cron/v3"
+)
+
+// SyncFunc syncs a department in a term
+type SyncFunc func(deptId int, term scrape.Term) error
+
+// Status is what the daemon knows about a department's syncs. It is saved
+// after every run, so it survives restarts.
+type Status struct {
+	LastRun      time.Time `json:"last_run"`
+	LastSuccess  time.Time `json:"last_success"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastDuration Duration  `json:"last_duration"`
+	Running      bool      `json:"running"`
+	Successes    int       `json:"successes"`
+	Failures     int       `json:"failures"`
+	Skipped      int       `json:"skipped"`
+}
+
+// Daemon runs the syncs in a Config on their cron schedules
+type Daemon struct {
+	config    Config
+	sync      SyncFunc
+	stateFile string
+	cron      *cron.Cron
+	slots     chan struct{}
+	started   time.Time
+
+	mu     sync.Mutex
+	status map[int]*Status
+	wg     sync.WaitGroup
+	stop   chan struct{}
+}
+
+// New creates a daemon, restoring the status of its departments from
+// stateFile if it exists
+func New(config Config, sync SyncFunc, stateFile string) (*Daemon, error) {
+	d := &Daemon{
+		config:    config,
+		sync:      sync,
+		stateFile: stateFile,
+		cron:      cron.New(),
+		slots:     make(chan struct{}, config.MaxConcurrent),
+		status:    make(map[int]*Status),
+		stop:      make(chan struct{}),
+	}
+	if err := d.load(); err != nil {
+		return nil, fmt.Errorf("failed to load state: %v", err)
+	}
+	for _, job := range config.Departments {
+		job := job
+		if _, found := d.status[job.Department]; !found {
+			d.status[job.Department] = &Status{}
+		}
+		if _, err := d.cron.AddFunc(job.Schedule, func() { d.run(job) }); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Start schedules the syncs. Departments that missed a run while the daemon
+// was down, or have never been synced, are synced right away.
+func (d *Daemon) Start() {
+	d.started = time.Now()
+	d.cron.Start()
+	for _, job := range d.config.Departments {
+		schedule, _ := cron.ParseStandard(job.Schedule)
+		d.mu.Lock()
+		missed := schedule.Next(d.status[job.Department].LastSuccess).Before(d.started)
+		d.mu.Unlock()
+		if missed {
+			go d.run(job)
+		}
+	}
+}
+
+// Stop stops scheduling syncs and waits for the running ones to finish.
+// Runs still waiting out their jitter or for a free slot are dropped.
+func (d *Daemon) Stop() {
+	<-d.cron.Stop().Done()
+	close(d.stop)
+	d.wg.Wait()
+}
+
+// run syncs every term of a job, unless the department is already syncing
+func (d *Daemon) run(job Job) {
+	d.mu.Lock()
+	status := d.status[job.Department]
+	if status.Running {
+		status.Skipped++
+		d.mu.Unlock()
+		log.Printf("Department %d is still syncing, skipping this run", job.Department)
+		return
+	}
+	status.Running = true
+	d.wg.Add(1)
+	d.mu.Unlock()
+	defer d.wg.Done()
+
+	if !d.wait(job) {
+		d.mu.Lock()
+		status.Running = false
+		d.mu.Unlock()
+		return
+	}
+	defer func() { <-d.slots }()
+
+	log.Printf("Syncing department %d", job.Department)
+	start := time.Now()
+	err := d.syncTerms(job)
+
+	d.mu.Lock()
+	status.Running = false
+	status.LastRun = start
+	status.LastDuration = Duration(time.Since(start))
+	if err != nil {
+		status.LastError = err.Error()
+		status.Failures++
+		log.Printf("Failed to sync department %d: %v", job.Department, err)
+	} else {
+		status.LastError = ""
+		status.LastSuccess = start
+		status.Successes++
+		log.Printf("Synced department %d in %v", job.Department, time.Since(start).Round(time.Second))
+	}
+	d.mu.Unlock()
+
+	if err := d.save(); err != nil {
+		log.Println("Failed to save state:", err)
+	}
+}
+
+// wait delays a run by a random jitter, then waits for a free slot. It
+// returns false if the daemon stopped in the meantime.
+func (d *Daemon) wait(job Job) bool {
+	if d.config.Jitter > 0 {
+		delay := time.Duration(rand.Int63n(int64(d.config.Jitter)))
+		select {
+		case <-time.After(delay):
+		case <-d.stop:
+			return false
+		}
+	}
+	select {
+	case d.slots <- struct{}{}:
+		return true
+	case <-d.stop:
+		return false
+	}
+}
+
+func (d *Daemon) syncTerms(job Job) (err error) {
+	// Scraping panics on unexpected pages, which shouldn't take down the
+	// other departments
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	for _, term := range job.Terms {
+		if err := d.sync(job.Department, term); err != nil {
+			return fmt.Errorf("%s: %v", term, err)
+		}
+	}
+	return nil
+}
+
+func (d *Daemon) load() error {
+	b, err := os.ReadFile(d.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &d.status); err != nil {
+		return err
+	}
+	for _, status := range d.status {
+		status.Running = false
+	}
+	return nil
+}
+
+// save writes the status of every department to the state file
+func (d *Daemon) save() error {
+	d.mu.Lock()
+	b, err := json.MarshalIndent(d.status, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.stateFile), 0755); err != nil {
+		return err
+	}
+	tmp := d.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.stateFile)
+}
+
+// Handler serves the daemon's health at /healthz and its metrics in the
+// Prometheus text format at /metrics
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", d.health)
+	mux.HandleFunc("/metrics", d.metrics)
+	return mux
+}
+
+func (d *Daemon) health(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Status      string          `json:"status"`
+		Uptime      Duration        `json:"uptime"`
+		Departments map[int]*Status `json:"departments"`
+	}{"ok", Duration(time.Since(d.started).Round(time.Second)), d.status})
+}
+
+func (d *Daemon) metrics(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var departments []int
+	for department := range d.status {
+		departments = append(departments, department)
+	}
+	sort.Ints(departments)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP isqool_sync_runs_total Department syncs by result.")
+	fmt.Fprintln(w, "# TYPE isqool_sync_runs_total counter")
+	for _, department := range departments {
+		status := d.status[department]
+		fmt.Fprintf(w, "isqool_sync_runs_total{department=\"%d\",result=\"success\"} %d\n", department, status.Successes)
+		fmt.Fprintf(w, "isqool_sync_runs_total{department=\"%d\",result=\"failure\"} %d\n", department, status.Failures)
+		fmt.Fprintf(w, "isqool_sync_runs_total{department=\"%d\",result=\"skipped\"} %d\n", department, status.Skipped)
+	}
+	fmt.Fprintln(w, "# HELP isqool_sync_running Whether a department is syncing.")
+	fmt.Fprintln(w, "# TYPE isqool_sync_running gauge")
+	for _, department := range departments {
+		running := 0
+		if d.status[department].Running {
+			running = 1
+		}
+		fmt.Fprintf(w, "isqool_sync_running{department=\"%d\"} %d\n", department, running)
+	}
+	fmt.Fprintln(w, "# HELP isqool_sync_last_success_timestamp_seconds When a department last synced successfully.")
+	fmt.Fprintln(w, "# TYPE isqool_sync_last_success_timestamp_seconds gauge")
+	for _, department := range departments {
+		var timestamp int64
+		if success := d.status[department].LastSuccess; !success.IsZero() {
+			timestamp = success.Unix()
+		}
+		fmt.Fprintf(w, "isqool_sync_last_success_timestamp_seconds{department=\"%d\"} %d\n", department, timestamp)
+	}
+	fmt.Fprintln(w, "# HELP isqool_sync_last_duration_seconds How long a department's last sync took.")
+	fmt.Fprintln(w, "# TYPE isqool_sync_last_duration_seconds gauge")
+	for _, department := range departments {
+		seconds := time.Duration(d.status[department].LastDuration).Seconds()
+		fmt.Fprintf(w, "isqool_sync_last_duration_seconds{department=\"%d\"} %g\n", department, seconds)
+	}
+	fmt.Fprintln(w, "# HELP isqool_uptime_seconds How long the daemon has been running.")
+	fmt.Fprintln(w, "# TYPE isqool_uptime_seconds gauge")
+	fmt.Fprintf(w, "isqool_uptime_seconds %g\n", time.Since(d.started).Seconds())
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// yearly is a schedule that won't come around during a test
+var yearly = Job{Department: 6502, Terms: []scrape.Term{"Fall 2023"}, Schedule: "0 0 1 1 *"}
+
+// newTestDaemon creates a daemon of one yearly job whose state file records
+// its last success
+func newTestDaemon(t *testing.T, lastSuccess time.Time, sync SyncFunc) *Daemon {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	b, _ := json.Marshal(map[int]*Status{yearly.Department: {LastSuccess: lastSuccess}})
+	if err := os.WriteFile(stateFile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(Config{MaxConcurrent: 1, Departments: []Job{yearly}}, sync, stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestStartMissedRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastSuccess time.Time
+		wantSync    bool
+	}{
+		{"never synced", time.Time{}, true},
+		{"missed a run", time.Now().AddDate(-2, 0, 0), true},
+		{"synced since the last run", time.Now(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synced := make(chan struct{}, 1)
+			d := newTestDaemon(t, tt.lastSuccess, func(int, scrape.Term) error {
+				synced <- struct{}{}
+				return nil
+			})
+			d.Start()
+			defer d.Stop()
+
+			// A run that was skipped never starts, so give it a moment
+			timeout := 5 * time.Second
+			if !tt.wantSync {
+				timeout = 100 * time.Millisecond
+			}
+			select {
+			case <-synced:
+				if !tt.wantSync {
+					t.Error("synced, want the run skipped")
+				}
+			case <-time.After(timeout):
+				if tt.wantSync {
+					t.Error("didn't sync the missed run")
+				}
+			}
+		})
+	}
+}
+
+func TestRunOverlapping(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls int
+	d := newTestDaemon(t, time.Now(), func(int, scrape.Term) error {
+		calls++
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		d.run(yearly)
+		close(done)
+	}()
+	<-started
+	d.run(yearly) // returns right away, as the first run is still syncing
+	close(release)
+	<-done
+
+	status := d.status[yearly.Department]
+	if calls != 1 || status.Successes != 1 || status.Skipped != 1 || status.Running {
+		t.Errorf("calls = %d, status = %+v, want one success and one skipped run", calls, status)
+	}
+}
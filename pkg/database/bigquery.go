@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"github.com/openswoop/isqool/pkg/scrape"
 	"google.golang.org/api/googleapi"
+	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -37,7 +40,73 @@ func NewBigQuery(projectID, datasetID string) (BigQuery, error) {
 	return bq, nil
 }
 
-func (bq BigQuery) InsertDepartments(departments []scrape.DeptSchedule, requestDept int, requestTerm string) error {
+// Close closes the BigQuery client
+func (bq BigQuery) Close() error {
+	return bq.client.Close()
+}
+
+// Tables are partitioned on the term id (see scrape.Term.Id) so that queries
+// filtering on a term range only scan the terms they need
+const (
+	termIdField    = "term_id"
+	termIdStart    = 200000
+	termIdEnd      = 210000
+	termIdInterval = 10
+)
+
+// arrivalsExpiration is how long a temp table of rows to merge is kept, so
+// ones left behind by a failed merge are cleaned up
+const arrivalsExpiration = 24 * time.Hour
+
+// termIdExpr builds the SQL computing a term column's id with a term scheme,
+// the same way scrape.TermScheme.Id does. Parts of term like "Summer A 2023"
+// share the id of their term, so the year is the last word and the season
+// the first.
+func termIdExpr(s scrape.TermScheme) string {
+	season := `SPLIT(term, " ")[OFFSET(0)]`
+	year := `CAST(ARRAY_REVERSE(SPLIT(term, " "))[OFFSET(0)] AS INT64)`
+
+	var seasons []string
+	for name := range s.Seasons {
+		seasons = append(seasons, name)
+	}
+	sort.Strings(seasons)
+	code := "CASE " + season
+	for _, name := range seasons {
+		code += fmt.Sprintf(" WHEN %s THEN %d", strconv.Quote(name), s.Seasons[name])
+	}
+	code += " END"
+
+	if len(s.NextYear) > 0 {
+		quoted := make([]string, len(s.NextYear))
+		for i, name := range s.NextYear {
+			quoted[i] = strconv.Quote(name)
+		}
+		year = fmt.Sprintf("(%s + IF(%s IN (%s), 1, 0))", year, season, strings.Join(quoted, ", "))
+	}
+	if s.ShortThrough != "" {
+		// Terms up to ShortThrough were coded with a single digit season
+		last := s.ShortThrough.Year()*100 + s.Seasons[s.ShortThrough.Season()]
+		for _, name := range s.NextYear {
+			if name == s.ShortThrough.Season() {
+				last += 100
+			}
+		}
+		code = fmt.Sprintf("IF(%[1]s * 100 + %[2]s <= %[3]d, DIV(%[2]s, 10), %[2]s)", year, code, last)
+	}
+	return fmt.Sprintf("%s * 100 + %s", year, code)
+}
+
+// tableClustering lists the columns each table is clustered on
+var tableClustering = map[string][]string{
+	"isqs":        {"course", "instructor"},
+	"grades":      {"course", "instructor"},
+	"departments": {"department", "course", "instructor"},
+	// Versions of the department schedules, see InsertHistory
+	"department_history": {"department", "course"},
+}
+
+func (bq BigQuery) InsertDepartments(departments []scrape.DeptSchedule, requestDept int, requestTerm scrape.Term) error {
 	matchClause := fmt.Sprintf(`
 		WHEN MATCHED AND t.instructor IS NULL THEN
 		  UPDATE
@@ -48,62 +117,339 @@ func (bq BigQuery) InsertDepartments(departments []scrape.DeptSchedule, requestD
 		  UPDATE SET meetings = s.meetings
 		WHEN NOT MATCHED BY SOURCE AND (t.department = %d AND t.term = "%s") THEN
 		  DELETE`, requestDept, requestTerm)
-	return bq.insert(scrape.DeptSchedule{}, "departments", departments, matchClause)
+	rows := make([]termRow, len(departments))
+	for i, department := range departments {
+		rows[i] = termRow{department.Term, department}
+	}
+	return bq.insert(scrape.DeptSchedule{}, "departments", rows, matchClause)
 }
 
 func (bq BigQuery) InsertISQs(isqs []scrape.CourseIsq) error {
-	return bq.insert(scrape.CourseIsq{}, "isqs", isqs, "")
+	rows := make([]termRow, len(isqs))
+	for i, isq := range isqs {
+		rows[i] = termRow{isq.Term, isq}
+	}
+	return bq.insert(scrape.CourseIsq{}, "isqs", rows, updateClause(scrape.CourseIsq{}))
 }
 
 func (bq BigQuery) InsertGrades(grades []scrape.CourseGrades) error {
-	return bq.insert(scrape.CourseGrades{}, "grades", grades, "")
+	rows := make([]termRow, len(grades))
+	for i, grade := range grades {
+		rows[i] = termRow{grade.Term, grade}
+	}
+	return bq.insert(scrape.CourseGrades{}, "grades", rows, updateClause(scrape.CourseGrades{}))
+}
+
+// updateClause updates every column of a matched row other than those
+// identifying its section, so rescraped values replace the stored ones
+func updateClause(st interface{}) string {
+	schema, err := bigquery.InferSchema(st)
+	if err != nil {
+		return ""
+	}
+	var set []string
+	for _, field := range schema {
+		switch field.Name {
+		case "course", "term", "crn":
+		default:
+			set = append(set, fmt.Sprintf("%[1]s = s.%[1]s", field.Name))
+		}
+	}
+	return `
+		WHEN MATCHED THEN
+		  UPDATE SET ` + strings.Join(set, ", ")
+}
+
+// InsertCatalog merges catalog entries into the catalog table. It isn't
+// partitioned, since entries are replaced rather than kept per term, and
+// joins to the other tables on their course column, as the catalog_sections
+// view does with the department schedules.
+func (bq BigQuery) InsertCatalog(entries []scrape.CatalogEntry) error {
+	schema, err := bigquery.InferSchema(scrape.CatalogEntry{})
+	if err != nil {
+		return fmt.Errorf("failed to infer schema: %v", err)
+	}
+	table := bq.dataset.Table("catalog")
+	if err := table.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema:     schema,
+		Clustering: &bigquery.Clustering{Fields: []string{"course"}},
+	}); err != nil {
+		if !isDuplicateError(err) {
+			return fmt.Errorf("failed to create table: %v", err)
+		}
+	}
+
+	view := bq.dataset.Table("catalog_sections")
+	if err := view.Create(bq.ctx, &bigquery.TableMetadata{
+		ViewQuery: fmt.Sprintf(`
+			SELECT d.*, c.description, c.levels, c.schedule_types, c.attributes,
+			  c.restrictions, c.prerequisites, c.corequisites
+			FROM %[1]s.departments d
+			LEFT JOIN %[1]s.catalog c ON c.course = d.course`, bq.dataset.DatasetID),
+	}); err != nil {
+		if !isDuplicateError(err) {
+			return fmt.Errorf("failed to create view: %v", err)
+		}
+	}
+
+	tempName := "catalog_" + strconv.Itoa(int(time.Now().Unix()))
+	newArrivals := bq.dataset.Table(tempName)
+	if err := newArrivals.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema:         schema,
+		ExpirationTime: time.Now().Add(arrivalsExpiration),
+	}); err != nil {
+		if !isDuplicateError(err) {
+			return fmt.Errorf("failed to create arrivals table: %v", err)
+		}
+	}
+	savers := make([]bigquery.ValueSaver, len(entries))
+	for i := range entries {
+		savers[i] = &bigquery.StructSaver{Schema: schema, Struct: entries[i]}
+	}
+	if err := newArrivals.Inserter().Put(bq.ctx, savers); err != nil {
+		return fmt.Errorf("failed to insert rows: %v", err)
+	}
+
+	var set []string
+	for _, field := range schema {
+		if field.Name != "course" {
+			set = append(set, fmt.Sprintf("%[1]s = s.%[1]s", field.Name))
+		}
+	}
+	q := bq.client.Query(fmt.Sprintf(`
+		MERGE %[1]s.catalog t
+		USING %[1]s.%[2]s s
+		ON t.course = s.course
+		WHEN MATCHED THEN
+		  UPDATE SET %[3]s
+		WHEN NOT MATCHED THEN
+		  INSERT ROW`, bq.dataset.DatasetID, tempName, strings.Join(set, ", ")))
+	if err := bq.wait(q.Run(bq.ctx)); err != nil {
+		return fmt.Errorf("failed to merge catalog: %v", err)
+	}
+	return nil
 }
 
-func (bq BigQuery) insert(st interface{}, tableName string, data interface{}, whenClause string) error {
+// InsertHistory merges versions of department schedule sections into the
+// department_history table. Versions are matched on their section and when
+// they became valid, so a version sent again once it ends is closed.
+func (bq BigQuery) InsertHistory(versions []scrape.ScheduleVersion) error {
+	schema, err := bigquery.InferSchema(scrape.ScheduleVersion{})
+	if err != nil {
+		return fmt.Errorf("failed to infer schema: %v", err)
+	}
+	schema = append(schema, &bigquery.FieldSchema{Name: termIdField, Type: bigquery.IntegerFieldType, Required: true})
+	table := bq.dataset.Table("department_history")
+	if err := table.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema: schema,
+		RangePartitioning: &bigquery.RangePartitioning{
+			Field: termIdField,
+			Range: &bigquery.RangePartitioningRange{
+				Start:    termIdStart,
+				End:      termIdEnd,
+				Interval: termIdInterval,
+			},
+		},
+		Clustering: &bigquery.Clustering{Fields: tableClustering["department_history"]},
+	}); err != nil {
+		if !isDuplicateError(err) {
+			return fmt.Errorf("failed to create table: %v", err)
+		}
+	}
+
+	tempName := "department_history_" + strconv.Itoa(int(time.Now().Unix()))
+	newArrivals := bq.dataset.Table(tempName)
+	if err := newArrivals.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema:         schema,
+		ExpirationTime: time.Now().Add(arrivalsExpiration),
+	}); err != nil {
+		if !isDuplicateError(err) {
+			return fmt.Errorf("failed to create arrivals table: %v", err)
+		}
+	}
+	savers := make([]bigquery.ValueSaver, len(versions))
+	for i := range versions {
+		savers[i] = termSaver{
+			StructSaver: bigquery.StructSaver{Schema: schema, Struct: versions[i]},
+			term:        versions[i].Term,
+			partitioned: true,
+		}
+	}
+	if err := newArrivals.Inserter().Put(bq.ctx, savers); err != nil {
+		return fmt.Errorf("failed to insert rows: %v", err)
+	}
+
+	q := bq.client.Query(fmt.Sprintf(`
+		MERGE %[1]s.department_history t
+		USING %[1]s.%[2]s s
+		ON t.course = s.course
+		  AND t.term = s.term
+		  AND t.crn = s.crn
+		  AND t.valid_from = s.valid_from
+		WHEN MATCHED THEN
+		  UPDATE SET valid_to = s.valid_to
+		WHEN NOT MATCHED THEN
+		  INSERT ROW`, bq.dataset.DatasetID, tempName))
+	if err := bq.wait(q.Run(bq.ctx)); err != nil {
+		return fmt.Errorf("failed to merge history: %v", err)
+	}
+	return nil
+}
+
+// InsertSnapshots appends snapshots of section seats to the
+// section_snapshots table, which is partitioned by the day they were taken
+func (bq BigQuery) InsertSnapshots(snapshots []scrape.SectionSnapshot) error {
+	schema, err := bigquery.InferSchema(scrape.SectionSnapshot{})
+	if err != nil {
+		return fmt.Errorf("failed to infer schema: %v", err)
+	}
+	table := bq.dataset.Table("section_snapshots")
+	if err := table.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema:           schema,
+		TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType, Field: "taken_at"},
+		Clustering:       &bigquery.Clustering{Fields: []string{"department", "course"}},
+	}); err != nil {
+		if !isDuplicateError(err) {
+			return fmt.Errorf("failed to create table: %v", err)
+		}
+	}
+
+	savers := make([]bigquery.ValueSaver, len(snapshots))
+	for i := range snapshots {
+		savers[i] = &bigquery.StructSaver{Schema: schema, Struct: snapshots[i]}
+	}
+	if err := table.Inserter().Put(bq.ctx, savers); err != nil {
+		return fmt.Errorf("failed to insert rows: %v", err)
+	}
+	return nil
+}
+
+// MigratePartitions rebuilds any table created before partitioning was
+// introduced into the partitioned and clustered layout. The original table
+// is kept as a backup so the migration can be audited.
+func (bq BigQuery) MigratePartitions() error {
+	for tableName, clustering := range tableClustering {
+		table := bq.dataset.Table(tableName)
+		md, err := table.Metadata(bq.ctx)
+		if err != nil {
+			if isNotFoundError(err) {
+				continue // nothing to migrate, it'll be created on the next insert
+			}
+			return fmt.Errorf("failed to get %s metadata: %v", tableName, err)
+		}
+		if md.RangePartitioning != nil {
+			continue // already migrated
+		}
+
+		// Rebuild it into a new table with the term id computed, leaving the
+		// original untouched until that succeeds
+		suffix := strconv.Itoa(int(time.Now().Unix()))
+		rebuiltName := tableName + "_rebuilt_" + suffix
+		q := bq.client.Query(fmt.Sprintf(`
+			CREATE TABLE %[1]s.%[2]s
+			PARTITION BY RANGE_BUCKET(%[4]s, GENERATE_ARRAY(%[5]d, %[6]d, %[7]d))
+			CLUSTER BY %[8]s
+			AS SELECT *, %[9]s AS %[4]s FROM %[1]s.%[3]s`,
+			bq.dataset.DatasetID, rebuiltName, tableName, termIdField,
+			termIdStart, termIdEnd, termIdInterval, strings.Join(clustering, ", "), termIdExpr(scrape.Current.Terms)))
+		if err := bq.wait(q.Run(bq.ctx)); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %v", tableName, err)
+		}
+
+		// Swap it in, keeping the original as a backup
+		backupName := tableName + "_backup_" + suffix
+		q = bq.client.Query(fmt.Sprintf("ALTER TABLE %s.%s RENAME TO %s", bq.dataset.DatasetID, tableName, backupName))
+		if err := bq.wait(q.Run(bq.ctx)); err != nil {
+			return fmt.Errorf("failed to back up %s (rebuilt table kept in %s): %v", tableName, rebuiltName, err)
+		}
+		q = bq.client.Query(fmt.Sprintf("ALTER TABLE %s.%s RENAME TO %s", bq.dataset.DatasetID, rebuiltName, tableName))
+		if err := bq.wait(q.Run(bq.ctx)); err != nil {
+			return fmt.Errorf("failed to replace %s (original in %s, rebuilt in %s): %v",
+				tableName, backupName, rebuiltName, err)
+		}
+	}
+	return nil
+}
+
+func (bq BigQuery) insert(st interface{}, tableName string, data []termRow, whenClause string) error {
 	// Infer the table schema
 	schema, err := bigquery.InferSchema(st)
 	if err != nil {
 		return fmt.Errorf("failed to infer schema: %v", err)
 	}
+	schema = append(schema, &bigquery.FieldSchema{Name: termIdField, Type: bigquery.IntegerFieldType, Required: true})
 
-	// Get a reference to the table
+	// Get a reference to the table, creating it partitioned and clustered if needed
 	table := bq.dataset.Table(tableName)
-	if err := table.Create(bq.ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
+	if err := table.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema: schema,
+		RangePartitioning: &bigquery.RangePartitioning{
+			Field: termIdField,
+			Range: &bigquery.RangePartitioningRange{
+				Start:    termIdStart,
+				End:      termIdEnd,
+				Interval: termIdInterval,
+			},
+		},
+		Clustering: &bigquery.Clustering{Fields: tableClustering[tableName]},
+	}); err != nil {
 		if !isDuplicateError(err) {
 			return fmt.Errorf("failed to create table: %v", err)
 		}
 	}
 
+	// Tables that haven't been migrated yet don't have a term id column
+	md, err := table.Metadata(bq.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get table metadata: %v", err)
+	}
+	partitioned := md.RangePartitioning != nil
+	if !partitioned {
+		log.Printf("Warning: table %s is not partitioned; run `isqool migrate` to rebuild it", tableName)
+		schema = schema[:len(schema)-1]
+	}
+
 	// Create a temp table
 	// Uses a different table each time: https://stackoverflow.com/a/51998193/5623874
 	tempName := tableName + "_" + strconv.Itoa(int(time.Now().Unix()))
 	newArrivals := bq.dataset.Table(tempName)
-	if err := newArrivals.Create(bq.ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
+	if err := newArrivals.Create(bq.ctx, &bigquery.TableMetadata{
+		Schema:         schema,
+		ExpirationTime: time.Now().Add(arrivalsExpiration),
+	}); err != nil {
 		if !isDuplicateError(err) {
 			return fmt.Errorf("failed to create arrivals table: %v", err)
 		}
 	}
 
 	// Upload data
+	savers := make([]bigquery.ValueSaver, len(data))
+	for i, row := range data {
+		savers[i] = termSaver{
+			StructSaver: bigquery.StructSaver{Schema: schema, Struct: row.data},
+			term:        row.term,
+			partitioned: partitioned,
+		}
+	}
 	u := newArrivals.Inserter()
-	if err := u.Put(bq.ctx, data); err != nil {
+	if err := u.Put(bq.ctx, savers); err != nil {
 		return fmt.Errorf("failed to insert rows: %v", err)
 	}
 
 	// Merge data
 	q := bq.client.Query(fmt.Sprintf(`
-		MERGE isqool.%s t
-		USING isqool.%s s
+		MERGE %[1]s.%[2]s t
+		USING %[1]s.%[3]s s
 		ON t.course = s.course
 		  AND t.term = s.term
 		  AND t.crn = s.crn
 		  AND (t.instructor = s.instructor
 		    OR t.instructor IS NULL)
-		%s
+		%[4]s
 		WHEN NOT MATCHED THEN
-		  INSERT ROW`, tableName, tempName, whenClause))
-	if _, err := q.Run(bq.ctx); err != nil { // TODO return status
-		panic(fmt.Errorf("failed to execute query: %v", err))
+		  INSERT ROW`, bq.dataset.DatasetID, tableName, tempName, whenClause))
+	if err := bq.wait(q.Run(bq.ctx)); err != nil {
+		return fmt.Errorf("failed to merge %s: %v", tableName, err)
 	}
 
 	// Don't delete the temp table so we can manually audit insertions
@@ -114,6 +460,52 @@ func (bq BigQuery) insert(st interface{}, tableName string, data interface{}, wh
 	return nil
 }
 
+// wait blocks until a job has finished and returns its error, if any
+func (bq BigQuery) wait(job *bigquery.Job, err error) error {
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(bq.ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// termRow is a row to be inserted along with the term it belongs to
+type termRow struct {
+	term scrape.Term
+	data interface{}
+}
+
+// termSaver saves a row with its term id so it lands in the right partition
+type termSaver struct {
+	bigquery.StructSaver
+	term        scrape.Term
+	partitioned bool
+}
+
+func (s termSaver) Save() (map[string]bigquery.Value, string, error) {
+	row, insertID, err := s.StructSaver.Save()
+	if err != nil || !s.partitioned {
+		return row, insertID, err
+	}
+	termId, err := s.term.Id()
+	if err != nil {
+		return nil, "", err
+	}
+	row[termIdField] = termId
+	return row, insertID, nil
+}
+
+func isNotFoundError(err error) bool {
+	if e, ok := err.(*googleapi.Error); ok {
+		return e.Code == 404
+	} else {
+		return false
+	}
+}
+
 func isDuplicateError(err error) bool {
 	if e, ok := err.(*googleapi.Error); ok {
 		return e.Code == 409
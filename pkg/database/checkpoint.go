@@ -0,0 +1,52 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"time"
+)
+
+// Checkpoint holds the rows scraped by a completed unit of work of a sync,
+// such as a course or a department's term, so a failed sync can resume
+type Checkpoint struct {
+	Sync    string `db:"sync"`
+	Unit    string `db:"unit"`
+	Rows    []byte `db:"rows"`
+	SavedAt int64  `db:"saved_at"`
+}
+
+// SaveCheckpoint records that a unit of a sync is done, along with its rows
+func (s Sqlite) SaveCheckpoint(sync, unit string, rows interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rows); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("insert or replace into checkpoints (sync, unit, rows, saved_at) values (?, ?, ?, ?)",
+		sync, unit, buf.Bytes(), time.Now().Unix())
+	return err
+}
+
+// LoadCheckpoint decodes the rows of a completed unit of a sync into rows,
+// returning false if the unit hasn't been done
+func (s Sqlite) LoadCheckpoint(sync, unit string, rows interface{}) (bool, error) {
+	var checkpoint Checkpoint
+	err := s.dbmap.SelectOne(&checkpoint, "select * from checkpoints where sync = ? and unit = ?", sync, unit)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, gob.NewDecoder(bytes.NewReader(checkpoint.Rows)).Decode(rows)
+}
+
+// CountCheckpoints returns how many units of a sync are done
+func (s Sqlite) CountCheckpoints(sync string) (int64, error) {
+	return s.dbmap.SelectInt("select count(*) from checkpoints where sync = ?", sync)
+}
+
+// ClearCheckpoints forgets the completed units of a sync
+func (s Sqlite) ClearCheckpoints(sync string) error {
+	_, err := s.db.Exec("delete from checkpoints where sync = ?", sync)
+	return err
+}
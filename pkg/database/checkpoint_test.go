@@ -0,0 +1,68 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+func TestCheckpoints(t *testing.T) {
+	sqlite := newTestSqlite(t)
+	smith := scrapetest.Section(scrapetest.Course("COP2220", 10001, "Smith"),
+		scrapetest.Meeting("MW", 1030, 1145, 1200, scrapetest.TermStart, scrapetest.TermEnd))
+	doe := scrapetest.Section(scrapetest.Course("COP2220", 10002, ""))
+
+	for _, checkpoint := range []struct {
+		sync, unit string
+		rows       []scrape.DeptSchedule
+	}{
+		{"departments", "6502/Fall 2023", []scrape.DeptSchedule{doe}},
+		{"departments", "6502/Fall 2023", []scrape.DeptSchedule{smith, doe}}, // redone
+		{"departments", "6502/Spring 2024", nil},
+		{"history", "6502/Fall 2023", []scrape.DeptSchedule{smith}},
+	} {
+		if err := sqlite.SaveCheckpoint(checkpoint.sync, checkpoint.unit, checkpoint.rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		sync, unit string
+		want       []scrape.DeptSchedule
+		wantFound  bool
+	}{
+		{"redone unit", "departments", "6502/Fall 2023", []scrape.DeptSchedule{smith, doe}, true},
+		{"unit without rows", "departments", "6502/Spring 2024", nil, true},
+		{"other sync", "history", "6502/Fall 2023", []scrape.DeptSchedule{smith}, true},
+		{"not done", "departments", "6502/Summer 2024", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []scrape.DeptSchedule
+			found, err := sqlite.LoadCheckpoint(tt.sync, tt.unit, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantFound || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadCheckpoint() = %+v, %t, want %+v, %t", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+
+	// Clearing a sync leaves the others to resume
+	if n, err := sqlite.CountCheckpoints("departments"); err != nil || n != 2 {
+		t.Errorf("CountCheckpoints() = %d, %v, want 2", n, err)
+	}
+	if err := sqlite.ClearCheckpoints("departments"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := sqlite.CountCheckpoints("departments"); err != nil || n != 0 {
+		t.Errorf("CountCheckpoints() after clearing = %d, %v, want 0", n, err)
+	}
+	if n, err := sqlite.CountCheckpoints("history"); err != nil || n != 1 {
+		t.Errorf("CountCheckpoints() of another sync = %d, %v, want 1", n, err)
+	}
+}
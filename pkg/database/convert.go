@@ -0,0 +1,168 @@
+package database
+
+import (
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/go-gorp/gorp/v3"
+	"github.com/openswoop/isqool/pkg/scrape"
+	"time"
+)
+
+// typeConverter maps the BigQuery null types and repeated fields used by the
+// scrape package to column types SQLite understands
+type typeConverter struct{}
+
+func (typeConverter) ToDb(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case bigquery.NullString:
+		if !v.Valid {
+			return nil, nil
+		}
+		return v.StringVal, nil
+	case bigquery.NullInt64:
+		if !v.Valid {
+			return nil, nil
+		}
+		return v.Int64, nil
+	case bigquery.NullTimestamp:
+		if !v.Valid {
+			return nil, nil
+		}
+		return timestampString(v.Timestamp), nil
+	case []scrape.Meeting:
+		meetings := make([]meetingJson, len(v))
+		for i, m := range v {
+			meetings[i] = meetingJson{
+				Type:      m.Type,
+				BeginDate: dateString(m.BeginDate),
+				EndDate:   dateString(m.EndDate),
+				Days:      m.Days,
+				BeginTime: m.BeginTime,
+				EndTime:   m.EndTime,
+				Building:  m.Building,
+				Room:      m.Room,
+			}
+		}
+		b, err := json.Marshal(meetings)
+		return string(b), err
+	case scrape.List:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	return val, nil
+}
+
+func (typeConverter) FromDb(target interface{}) (gorp.CustomScanner, bool) {
+	switch target.(type) {
+	case *bigquery.NullString:
+		binder := func(holder, target interface{}) error {
+			s := holder.(*sql.NullString)
+			*target.(*bigquery.NullString) = bigquery.NullString{StringVal: s.String, Valid: s.Valid}
+			return nil
+		}
+		return gorp.CustomScanner{Holder: new(sql.NullString), Target: target, Binder: binder}, true
+	case *bigquery.NullInt64:
+		binder := func(holder, target interface{}) error {
+			i := holder.(*sql.NullInt64)
+			*target.(*bigquery.NullInt64) = bigquery.NullInt64{Int64: i.Int64, Valid: i.Valid}
+			return nil
+		}
+		return gorp.CustomScanner{Holder: new(sql.NullInt64), Target: target, Binder: binder}, true
+	case *bigquery.NullTimestamp:
+		binder := func(holder, target interface{}) error {
+			s := holder.(*sql.NullString)
+			if !s.Valid {
+				*target.(*bigquery.NullTimestamp) = bigquery.NullTimestamp{}
+				return nil
+			}
+			t, err := time.Parse(time.RFC3339Nano, s.String)
+			if err != nil {
+				return fmt.Errorf("unable to parse timestamp: %v", err)
+			}
+			*target.(*bigquery.NullTimestamp) = bigquery.NullTimestamp{Timestamp: t, Valid: true}
+			return nil
+		}
+		return gorp.CustomScanner{Holder: new(sql.NullString), Target: target, Binder: binder}, true
+	case *[]scrape.Meeting:
+		binder := func(holder, target interface{}) error {
+			s := holder.(*sql.NullString)
+			if !s.Valid {
+				return nil
+			}
+			var meetings []meetingJson
+			if err := json.Unmarshal([]byte(s.String), &meetings); err != nil {
+				return fmt.Errorf("unable to parse meetings: %v", err)
+			}
+			result := make([]scrape.Meeting, len(meetings))
+			for i, m := range meetings {
+				beginDate, err := parseDate(m.BeginDate)
+				if err != nil {
+					return err
+				}
+				endDate, err := parseDate(m.EndDate)
+				if err != nil {
+					return err
+				}
+				result[i] = scrape.Meeting{
+					Type:      m.Type,
+					BeginDate: beginDate,
+					EndDate:   endDate,
+					Days:      m.Days,
+					BeginTime: m.BeginTime,
+					EndTime:   m.EndTime,
+					Building:  m.Building,
+					Room:      m.Room,
+				}
+			}
+			*target.(*[]scrape.Meeting) = result
+			return nil
+		}
+		return gorp.CustomScanner{Holder: new(sql.NullString), Target: target, Binder: binder}, true
+	case *scrape.List:
+		binder := func(holder, target interface{}) error {
+			s := holder.(*sql.NullString)
+			if !s.Valid {
+				return nil
+			}
+			return json.Unmarshal([]byte(s.String), target)
+		}
+		return gorp.CustomScanner{Holder: new(sql.NullString), Target: target, Binder: binder}, true
+	}
+	return gorp.CustomScanner{}, false
+}
+
+// meetingJson is how a meeting is stored in SQLite. Meetings without dates
+// have zero dates, which civil.Date can't round-trip through JSON.
+type meetingJson struct {
+	Type      string              `json:"type"`
+	BeginDate string              `json:"begin_date"`
+	EndDate   string              `json:"end_date"`
+	Days      bigquery.NullString `json:"days"`
+	BeginTime bigquery.NullTime   `json:"begin_time"`
+	EndTime   bigquery.NullTime   `json:"end_time"`
+	Building  bigquery.NullString `json:"building"`
+	Room      bigquery.NullInt64  `json:"room"`
+}
+
+// timestampString is how a nullable timestamp is stored in SQLite, which
+// gorp maps to a text column
+func timestampString(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func dateString(d civil.Date) string {
+	if d == (civil.Date{}) {
+		return ""
+	}
+	return d.String()
+}
+
+func parseDate(s string) (civil.Date, error) {
+	if s == "" {
+		return civil.Date{}, nil
+	}
+	return civil.ParseDate(s)
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"github.com/openswoop/isqool/pkg/scrape"
 	"io"
+	"time"
 )
 
 type Database interface {
@@ -10,4 +11,20 @@ type Database interface {
 	SaveIsqs([]scrape.CourseIsq) error
 	SaveGrades([]scrape.CourseGrades) error
 	SaveSchedules([]scrape.CourseSchedule) error
+	SaveDepartments([]scrape.DeptSchedule) error
+	SaveCatalog([]scrape.CatalogEntry) error
+	SaveSnapshots([]scrape.SectionSnapshot) error
+	SaveHistory(departments []scrape.DeptSchedule, at time.Time) error
+
+	LoadIsqs(courses []string) ([]scrape.CourseIsq, error)
+	LoadGrades(courses []string) ([]scrape.CourseGrades, error)
+	LoadSchedules(courses []string) ([]scrape.CourseSchedule, error)
+	LoadDepartments(deptId int, terms []scrape.Term) ([]scrape.DeptSchedule, error)
+	LoadCatalog(courses []string) ([]scrape.CatalogEntry, error)
+	LoadSnapshots(deptId int, term scrape.Term) ([]scrape.SectionSnapshot, error)
+	LoadHistory(deptId int, terms []scrape.Term) ([]scrape.ScheduleVersion, error)
+	LoadInstructors(instructors []string) ([]scrape.CourseIsq, []scrape.CourseGrades, error)
+	Terms() ([]scrape.Term, error)
+	Courses() ([]string, error)
+	Departments() ([]int, error)
 }
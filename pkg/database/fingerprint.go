@@ -0,0 +1,56 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Fingerprint is a hash of the data last synced from a page, so that
+// unchanged pages can be skipped by later syncs
+type Fingerprint struct {
+	Kind     string `db:"kind"`
+	Key      string `db:"key"`
+	Hash     string `db:"hash"`
+	SyncedAt int64  `db:"synced_at"`
+}
+
+// NewFingerprint hashes the rows scraped from a page
+func NewFingerprint(kind, key string, rows interface{}) (Fingerprint, error) {
+	b, err := json.Marshal(rows)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	sum := sha256.Sum256(b)
+	return Fingerprint{kind, key, hex.EncodeToString(sum[:]), time.Now().Unix()}, nil
+}
+
+// Fingerprint returns the last synced fingerprint of a page, if there is one
+func (s Sqlite) Fingerprint(kind, key string) (Fingerprint, bool, error) {
+	var fingerprint Fingerprint
+	err := s.dbmap.SelectOne(&fingerprint, "select * from fingerprints where kind = ? and key = ?", kind, key)
+	if err == sql.ErrNoRows {
+		return fingerprint, false, nil
+	}
+	return fingerprint, err == nil, err
+}
+
+// SaveFingerprints records that pages were synced, replacing their previous
+// fingerprints
+func (s Sqlite) SaveFingerprints(fingerprints []Fingerprint) error {
+	tx, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	for _, f := range fingerprints {
+		_, err := tx.Exec("insert or replace into fingerprints (kind, key, hash, synced_at) values (?, ?, ?, ?)",
+			f.Kind, f.Key, f.Hash, f.SyncedAt)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+// newTestSqlite opens an empty database that's removed after the test
+func newTestSqlite(t *testing.T) Sqlite {
+	sqlite := NewSqlite(filepath.Join(t.TempDir(), "isqool.db"))
+	t.Cleanup(func() { _ = sqlite.Close() })
+	return sqlite
+}
+
+func TestNewFingerprint(t *testing.T) {
+	section := scrapetest.Section(scrapetest.Course("COP2220", 10001, "Smith"))
+	retitled := section
+	retitled.Title = "Intro to Programming"
+	base, err := NewFingerprint("department", "6502/Fall 2023", []scrape.DeptSchedule{section})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		kind     string
+		key      string
+		rows     []scrape.DeptSchedule
+		wantSame bool
+	}{
+		{"same rows", "department", "6502/Fall 2023", []scrape.DeptSchedule{section}, true},
+		{"changed row", "department", "6502/Fall 2023", []scrape.DeptSchedule{retitled}, false},
+		{"added row", "department", "6502/Fall 2023", []scrape.DeptSchedule{section, retitled}, false},
+		{"other page", "department", "6502/Spring 2024", []scrape.DeptSchedule{section}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFingerprint(tt.kind, tt.key, tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Kind != tt.kind || f.Key != tt.key || f.SyncedAt == 0 {
+				t.Errorf("NewFingerprint() = %+v, want kind %s and key %s synced now", f, tt.kind, tt.key)
+			}
+			if same := f.Hash == base.Hash; same != tt.wantSame {
+				t.Errorf("same hash = %t, want %t", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestSaveFingerprints(t *testing.T) {
+	sqlite := newTestSqlite(t)
+	first := Fingerprint{"department", "6502/Fall 2023", "a", 100}
+	other := Fingerprint{"course", "COP2220", "b", 100}
+	if err := sqlite.SaveFingerprints([]Fingerprint{first, other}); err != nil {
+		t.Fatal(err)
+	}
+	resynced := Fingerprint{"department", "6502/Fall 2023", "c", 200}
+	if err := sqlite.SaveFingerprints([]Fingerprint{resynced}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		kind, key string
+		want      Fingerprint
+		wantFound bool
+	}{
+		{"replaced", "department", "6502/Fall 2023", resynced, true},
+		{"kept", "course", "COP2220", other, true},
+		{"other kind", "course", "6502/Fall 2023", Fingerprint{}, false},
+		{"never synced", "department", "6502/Spring 2024", Fingerprint{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := sqlite.Fingerprint(tt.kind, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("Fingerprint() = %+v, %t, want %+v, %t", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
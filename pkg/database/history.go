@@ -0,0 +1,122 @@
+package database
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/go-gorp/gorp/v3"
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// SaveHistory records how the schedules of every department and term present
+// in departments changed since they were last saved. The current versions of
+// sections that changed or disappeared are closed at the given time, and
+// new or changed sections get a version starting then. Wait counts change
+// too often to be worth a version of their own.
+func (s Sqlite) SaveHistory(departments []scrape.DeptSchedule, at time.Time) error {
+	var keys []deptTerm
+	schedules := make(map[deptTerm][]scrape.DeptSchedule)
+	for _, row := range departments {
+		key := deptTerm{row.Department, row.Term}
+		if _, found := schedules[key]; !found {
+			keys = append(keys, key)
+		}
+		schedules[key] = append(schedules[key], row)
+	}
+
+	tx, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		var current []scrape.ScheduleVersion
+		_, err := tx.Select(&current, `select * from department_history
+			where department = ? and term = ? and valid_to is null`, key.dept, key.term)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		open := make(map[versionKey]scrape.ScheduleVersion)
+		for _, version := range current {
+			open[toVersionKey(version.DeptSchedule)] = version
+		}
+
+		seen := make(map[versionKey]bool)
+		for _, row := range schedules[key] {
+			k := toVersionKey(row)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			if version, found := open[k]; found {
+				delete(open, k)
+				if sameVersion(version.DeptSchedule, row) {
+					continue
+				}
+				if err := closeVersion(tx, key, k, at); err != nil {
+					_ = tx.Rollback()
+					return err
+				}
+			}
+			if err := tx.Insert(&scrape.ScheduleVersion{DeptSchedule: row, ValidFrom: at}); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+
+		// Whatever is left was removed from the schedule
+		for k := range open {
+			if err := closeVersion(tx, key, k, at); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadHistory returns every version of a department's sections, optionally
+// limited to the given terms, oldest first
+func (s Sqlite) LoadHistory(deptId int, terms []scrape.Term) ([]scrape.ScheduleVersion, error) {
+	var versions []scrape.ScheduleVersion
+	query, args := "select * from department_history where department = ?", []interface{}{deptId}
+	if len(terms) > 0 {
+		names := make([]string, len(terms))
+		for i, term := range terms {
+			names[i] = string(term)
+		}
+		var termArgs []interface{}
+		query, termArgs = inClause(query+" and term in", names)
+		args = append(args, termArgs...)
+	}
+	_, err := s.dbmap.Select(&versions, query+" order by valid_from", args...)
+	return versions, err
+}
+
+// versionKey identifies a section within a department's schedule for a term
+type versionKey struct {
+	name string
+	crn  int
+}
+
+func toVersionKey(row scrape.DeptSchedule) versionKey {
+	return versionKey{row.Name, row.Crn}
+}
+
+// closeVersion ends the current version of a section
+func closeVersion(tx *gorp.Transaction, dt deptTerm, k versionKey, at time.Time) error {
+	_, err := tx.Exec(`update department_history set valid_to = ?
+		where department = ? and term = ? and name = ? and crn = ? and valid_to is null`,
+		timestampString(at), dt.dept, dt.term, k.name, k.crn)
+	return err
+}
+
+// sameVersion reports whether two rows of a section are the same, ignoring
+// the wait count
+func sameVersion(a, b scrape.DeptSchedule) bool {
+	a.WaitCount, b.WaitCount = 0, 0
+	if len(a.Meetings) == 0 && len(b.Meetings) == 0 {
+		a.Meetings, b.Meetings = nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
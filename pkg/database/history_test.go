@@ -0,0 +1,87 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+// section makes a section of COP2220 meeting in a room, with a wait list
+func section(crn int, room int64, waitCount int) scrape.DeptSchedule {
+	s := scrapetest.Section(scrapetest.Course("COP2220", crn, "Liu"),
+		scrapetest.Meeting("MW", 1030, 1145, room, scrapetest.TermStart, scrapetest.TermEnd))
+	s.WaitCount = waitCount
+	return s
+}
+
+func TestSaveHistory(t *testing.T) {
+	t0 := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	t1, t2, t3 := t0.Add(24*time.Hour), t0.Add(48*time.Hour), t0.Add(72*time.Hour)
+
+	// Save what each sync saw of the department's schedule
+	sqlite := newTestSqlite(t)
+	for _, sync := range []struct {
+		at       time.Time
+		sections []scrape.DeptSchedule
+	}{
+		{t0, []scrape.DeptSchedule{section(1, 1200, 0), section(2, 1200, 0)}},
+		// Section 1 moved and section 2's wait list grew
+		{t1, []scrape.DeptSchedule{section(1, 1400, 0), section(2, 1200, 5)}},
+		// Section 2 was removed and section 3 added
+		{t2, []scrape.DeptSchedule{section(1, 1400, 0), section(3, 1300, 0)}},
+		// Section 2 was added back, and section 3 seen twice
+		{t3, []scrape.DeptSchedule{section(1, 1400, 0), section(2, 1200, 0), section(3, 1300, 0), section(3, 1300, 0)}},
+	} {
+		if err := sqlite.SaveHistory(sync.sections, sync.at); err != nil {
+			t.Fatalf("SaveHistory() at %v: %v", sync.at, err)
+		}
+	}
+
+	type span struct {
+		crn      int
+		room     int64
+		from, to time.Time
+	}
+	tests := []struct {
+		name  string
+		dept  int
+		terms []scrape.Term
+		want  []span
+	}{
+		{
+			name:  "term",
+			dept:  scrapetest.Department,
+			terms: []scrape.Term{scrapetest.Term},
+			want: []span{
+				{1, 1200, t0, t1},
+				{2, 1200, t0, t2},
+				{1, 1400, t1, time.Time{}},
+				{3, 1300, t2, time.Time{}},
+				{2, 1200, t3, time.Time{}},
+			},
+		},
+		{name: "other term", dept: scrapetest.Department, terms: []scrape.Term{"Spring 2024"}},
+		{name: "other department", dept: 6501},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := sqlite.LoadHistory(tt.dept, tt.terms)
+			if err != nil {
+				t.Fatalf("LoadHistory(): %v", err)
+			}
+			if len(versions) != len(tt.want) {
+				t.Fatalf("LoadHistory() returned %d versions, want %d", len(versions), len(tt.want))
+			}
+			for i, v := range versions {
+				got := span{v.Crn, v.Meetings[0].Room.Int64, v.ValidFrom, v.ValidTo.Timestamp}
+				want := tt.want[i]
+				if got.crn != want.crn || got.room != want.room || !got.from.Equal(want.from) ||
+					!got.to.Equal(want.to) || v.ValidTo.Valid != !want.to.IsZero() {
+					t.Errorf("version %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
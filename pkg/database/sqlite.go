@@ -7,6 +7,7 @@ import (
 	"github.com/mattn/go-sqlite3"
 	"github.com/openswoop/isqool/pkg/scrape"
 	"log"
+	"strings"
 )
 
 type Sqlite struct {
@@ -22,13 +23,20 @@ func NewSqlite(file string) Sqlite {
 	if err != nil {
 		log.Panic("Unable to connect to database: ", err)
 	}
+	db.SetMaxOpenConns(1) // SQLite only allows one writer at a time
 	sqlite.db = db
 
 	// Initialize the database mapping, creating the tables if it's our first run
-	dbmap := &gorp.DbMap{Db: db, Dialect: gorp.SqliteDialect{}}
+	dbmap := &gorp.DbMap{Db: db, Dialect: gorp.SqliteDialect{}, TypeConverter: typeConverter{}}
 	dbmap.AddTableWithName(scrape.CourseIsq{}, "isq").SetUniqueTogether("Crn", "Term", "Instructor", "Name")
 	dbmap.AddTableWithName(scrape.CourseGrades{}, "grades").SetUniqueTogether("Crn", "Term", "Instructor", "Name")
 	dbmap.AddTableWithName(scrape.CourseSchedule{}, "schedules").SetUniqueTogether("Crn", "Term", "Instructor", "Name")
+	dbmap.AddTableWithName(scrape.DeptSchedule{}, "departments").SetUniqueTogether("Crn", "Term", "Name")
+	dbmap.AddTableWithName(scrape.CatalogEntry{}, "catalog").SetKeys(false, "Name")
+	dbmap.AddTableWithName(scrape.SectionSnapshot{}, "section_snapshots")
+	dbmap.AddTableWithName(scrape.ScheduleVersion{}, "department_history")
+	dbmap.AddTableWithName(Fingerprint{}, "fingerprints").SetKeys(false, "Kind", "Key")
+	dbmap.AddTableWithName(Checkpoint{}, "checkpoints").SetKeys(false, "Sync", "Unit")
 	err = dbmap.CreateTablesIfNotExists()
 	if err != nil {
 		log.Panic("Unable to create tables: ", err)
@@ -38,47 +46,258 @@ func NewSqlite(file string) Sqlite {
 	return sqlite
 }
 
+// SaveIsqs saves ISQs, replacing those already stored for the same sections
+// so that rescraped values aren't lost
 func (s Sqlite) SaveIsqs(isqs []scrape.CourseIsq) error {
-	var insertData = make([]interface{}, len(isqs))
+	rows := make([]courseRow, len(isqs))
 	for i := range isqs {
-		insertData = append(insertData, &isqs[i])
+		rows[i] = courseRow{isqs[i].Course, &isqs[i]}
 	}
-	return s.save(insertData)
+	return s.replace("isq", rows)
 }
 
+// SaveGrades saves grades, replacing those already stored for the same
+// sections
 func (s Sqlite) SaveGrades(grades []scrape.CourseGrades) error {
-	var insertData = make([]interface{}, len(grades))
+	rows := make([]courseRow, len(grades))
 	for i := range grades {
-		insertData = append(insertData, &grades[i])
+		rows[i] = courseRow{grades[i].Course, &grades[i]}
 	}
-	return s.save(insertData)
+	return s.replace("grades", rows)
 }
 
+// SaveSchedules saves schedules, replacing those already stored for the same
+// sections
 func (s Sqlite) SaveSchedules(schedules []scrape.CourseSchedule) error {
-	var insertData = make([]interface{}, len(schedules))
+	rows := make([]courseRow, len(schedules))
 	for i := range schedules {
-		insertData = append(insertData, &schedules[i])
+		rows[i] = courseRow{schedules[i].Course, &schedules[i]}
+	}
+	return s.replace("schedules", rows)
+}
+
+// deptTerm identifies the schedule of a department in a term
+type deptTerm struct {
+	dept int
+	term scrape.Term
+}
+
+// SaveDepartments replaces the stored schedules of every department and term
+// present in departments, so cancelled sections don't linger
+func (s Sqlite) SaveDepartments(departments []scrape.DeptSchedule) error {
+	tx, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	seen := make(map[deptTerm]bool)
+	for i := range departments {
+		key := deptTerm{departments[i].Department, departments[i].Term}
+		if !seen[key] {
+			seen[key] = true
+			_, err := tx.Exec("delete from departments where department = ? and term = ?", key.dept, key.term)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		if err := insert(tx, &departments[i]); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveCatalog saves catalog entries, replacing those already stored for the
+// same courses
+func (s Sqlite) SaveCatalog(entries []scrape.CatalogEntry) error {
+	tx, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		n, err := tx.Update(&entries[i])
+		if err == nil && n == 0 {
+			err = tx.Insert(&entries[i])
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveSnapshots adds snapshots of the seats in sections
+func (s Sqlite) SaveSnapshots(snapshots []scrape.SectionSnapshot) error {
+	var insertData = make([]interface{}, 0, len(snapshots))
+	for i := range snapshots {
+		insertData = append(insertData, &snapshots[i])
 	}
 	return s.save(insertData)
 }
 
-func (s Sqlite) save(rows []interface{}) error {
+// LoadIsqs returns the stored ISQs of the given courses
+func (s Sqlite) LoadIsqs(courses []string) ([]scrape.CourseIsq, error) {
+	var isqs []scrape.CourseIsq
+	query, args := inClause("select * from isq where name in", courses)
+	_, err := s.dbmap.Select(&isqs, query, args...)
+	return isqs, err
+}
+
+// LoadGrades returns the stored grades of the given courses
+func (s Sqlite) LoadGrades(courses []string) ([]scrape.CourseGrades, error) {
+	var grades []scrape.CourseGrades
+	query, args := inClause("select * from grades where name in", courses)
+	_, err := s.dbmap.Select(&grades, query, args...)
+	return grades, err
+}
+
+// LoadSchedules returns the stored schedules of the given courses
+func (s Sqlite) LoadSchedules(courses []string) ([]scrape.CourseSchedule, error) {
+	var schedules []scrape.CourseSchedule
+	query, args := inClause("select * from schedules where name in", courses)
+	_, err := s.dbmap.Select(&schedules, query, args...)
+	return schedules, err
+}
+
+// LoadDepartments returns the stored schedules of a department, optionally
+// limited to the given terms
+func (s Sqlite) LoadDepartments(deptId int, terms []scrape.Term) ([]scrape.DeptSchedule, error) {
+	var departments []scrape.DeptSchedule
+	query, args := "select * from departments where department = ?", []interface{}{deptId}
+	if len(terms) > 0 {
+		names := make([]string, len(terms))
+		for i, term := range terms {
+			names[i] = string(term)
+		}
+		var termArgs []interface{}
+		query, termArgs = inClause(query+" and term in", names)
+		args = append(args, termArgs...)
+	}
+	_, err := s.dbmap.Select(&departments, query, args...)
+	return departments, err
+}
+
+// LoadCatalog returns the stored catalog entries of the given courses
+func (s Sqlite) LoadCatalog(courses []string) ([]scrape.CatalogEntry, error) {
+	var entries []scrape.CatalogEntry
+	query, args := inClause("select * from catalog where name in", courses)
+	_, err := s.dbmap.Select(&entries, query, args...)
+	return entries, err
+}
+
+// LoadSnapshots returns the snapshots of a department's sections in a term,
+// oldest first
+func (s Sqlite) LoadSnapshots(deptId int, term scrape.Term) ([]scrape.SectionSnapshot, error) {
+	var snapshots []scrape.SectionSnapshot
+	_, err := s.dbmap.Select(&snapshots,
+		"select * from section_snapshots where department = ? and term = ? order by taken_at", deptId, term)
+	return snapshots, err
+}
+
+// LoadInstructors returns the stored ISQs and grades of the sections taught
+// by the given instructors, by last name
+func (s Sqlite) LoadInstructors(instructors []string) ([]scrape.CourseIsq, []scrape.CourseGrades, error) {
+	var isqs []scrape.CourseIsq
+	query, args := inClause("select * from isq where instructor in", instructors)
+	if _, err := s.dbmap.Select(&isqs, query, args...); err != nil {
+		return nil, nil, err
+	}
+	var grades []scrape.CourseGrades
+	query, args = inClause("select * from grades where instructor in", instructors)
+	if _, err := s.dbmap.Select(&grades, query, args...); err != nil {
+		return nil, nil, err
+	}
+	return isqs, grades, nil
+}
+
+// Terms returns every term with stored ISQs or department schedules
+func (s Sqlite) Terms() ([]scrape.Term, error) {
+	var terms []scrape.Term
+	_, err := s.dbmap.Select(&terms, "select term from isq union select term from departments")
+	return terms, err
+}
+
+// Courses returns the name of every course with stored data
+func (s Sqlite) Courses() ([]string, error) {
+	var courses []string
+	_, err := s.dbmap.Select(&courses, `select name from isq union select name from grades
+		union select name from schedules union select name from departments order by name`)
+	return courses, err
+}
+
+// Departments returns the ids of every department with stored schedules
+func (s Sqlite) Departments() ([]int, error) {
+	var departments []int
+	_, err := s.dbmap.Select(&departments, "select distinct department from departments order by department")
+	return departments, err
+}
+
+// courseRow is a row to be saved along with the section it belongs to
+type courseRow struct {
+	course scrape.Course
+	data   interface{}
+}
+
+// replace saves rows into a table unique by section, deleting the rows
+// already stored for their sections first
+func (s Sqlite) replace(table string, rows []courseRow) error {
 	tx, err := s.dbmap.Begin()
 	if err != nil {
 		return err
 	}
 	for _, row := range rows {
-		err := tx.Insert(row)
-		var sqliteError sqlite3.Error
-		if errors.As(err, &sqliteError) {
-			if errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
-				continue // silently ignore duplicates
-			}
+		var instructor interface{}
+		if row.course.Instructor.Valid {
+			instructor = row.course.Instructor.StringVal
+		}
+		_, err := tx.Exec("delete from "+table+" where name = ? and term = ? and crn = ? and instructor is ?",
+			row.course.Name, row.course.Term, row.course.Crn, instructor)
+		if err == nil {
+			err = tx.Insert(row.data)
 		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s Sqlite) save(rows []interface{}) error {
+	tx, err := s.dbmap.Begin()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		_ = insert(tx, row) // best effort, as a bad row shouldn't cost the rest
 	}
 	return tx.Commit()
 }
 
+func insert(tx *gorp.Transaction, row interface{}) error {
+	err := tx.Insert(row)
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) {
+		if errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return nil // silently ignore duplicates
+		}
+	}
+	return err
+}
+
+// inClause appends a parenthesized list of placeholders for values to query
+func inClause(query string, values []string) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		args[i] = value
+	}
+	return query + " (" + strings.Join(placeholders, ", ") + ")", args
+}
+
 func (s Sqlite) Close() error {
 	return s.db.Close()
 }
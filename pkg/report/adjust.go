@@ -0,0 +1,148 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Adjusted holds a rating shrunk towards the rating of similar sections, so
+// sections with few responses don't rank above sections with many
+type Adjusted struct {
+	PriorRating    float64 `csv:"prior_rating" json:"prior_rating"`
+	AdjustedRating float64 `csv:"adjusted_rating" json:"adjusted_rating"`
+}
+
+// PriorLevel selects which sections make up the prior of a section
+type PriorLevel int
+
+const (
+	// PriorCourse uses the sections of the same course as the prior
+	PriorCourse PriorLevel = iota
+	// PriorAll uses every section in the input as the prior, e.g. all the
+	// courses of a department
+	PriorAll
+)
+
+func ParsePriorLevel(s string) (PriorLevel, error) {
+	switch s {
+	case "course":
+		return PriorCourse, nil
+	case "all":
+		return PriorAll, nil
+	default:
+		return 0, fmt.Errorf("%s is not a valid prior level (course, all)", s)
+	}
+}
+
+func (l PriorLevel) key(row CourseRow) string {
+	if l == PriorCourse {
+		return row.CsvCourse.Name
+	}
+	return ""
+}
+
+// responseTotals is the number of responses and the sum of their scores
+type responseTotals struct {
+	sections  int
+	responses float64
+	scores    float64
+}
+
+func (t *responseTotals) add(row CourseRow) {
+	percents := [5]float64{row.Percent1, row.Percent2, row.Percent3, row.Percent4, row.Percent5}
+	for i, percent := range percents {
+		count := percent / 100 * float64(row.Responded)
+		t.responses += count
+		t.scores += float64(i+1) * count
+	}
+	t.sections++
+}
+
+// adjustRatings computes the Bayesian adjusted rating of every section. Each
+// section's responses are combined with priorWeight pseudo-responses at the
+// mean rating of its prior group. If priorWeight is zero, the average number
+// of responses per section in the group is used.
+func adjustRatings(rows courseReport, level PriorLevel, priorWeight float64) {
+	priors := make(map[string]*responseTotals)
+	for _, row := range rows {
+		if row.Isq == nil {
+			continue
+		}
+		prior, found := priors[level.key(row)]
+		if !found {
+			prior = &responseTotals{}
+			priors[level.key(row)] = prior
+		}
+		prior.add(row)
+	}
+
+	for i, row := range rows {
+		if row.Isq == nil {
+			continue
+		}
+		prior := priors[level.key(row)]
+		if prior.responses == 0 {
+			continue
+		}
+		priorRating := prior.scores / prior.responses
+		weight := priorWeight
+		if weight <= 0 {
+			weight = prior.responses / float64(prior.sections)
+		}
+
+		var section responseTotals
+		section.add(row)
+		rows[i].Adjusted = &Adjusted{
+			PriorRating:    round2(priorRating),
+			AdjustedRating: round2((section.scores + weight*priorRating) / (section.responses + weight)),
+		}
+	}
+}
+
+type RankedSection struct {
+	Rank int `csv:"rank" json:"rank"`
+	CsvCourse
+	Responded int     `csv:"responded" json:"responded"`
+	Rating    float64 `csv:"rating" json:"rating"`
+	Adjusted
+}
+
+// RankSections orders sections by their adjusted rating, best first
+func RankSections(r CourseInput, level PriorLevel, priorWeight float64) []RankedSection {
+	r.FullJoin = false // only sections with an ISQ have a rating
+	rows := joinCourse(r)
+	adjustRatings(rows, level, priorWeight)
+
+	var ranked []RankedSection
+	for _, row := range rows {
+		if row.Adjusted == nil {
+			continue
+		}
+		ranked = append(ranked, RankedSection{
+			CsvCourse: row.CsvCourse,
+			Responded: row.Responded,
+			Rating:    row.Rating,
+			Adjusted:  *row.Adjusted,
+		})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].AdjustedRating > ranked[j].AdjustedRating
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	return ranked
+}
+
+// WriteRankingTable prints the ranked sections as an aligned table
+func WriteRankingTable(w io.Writer, ranked []RankedSection) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tCOURSE\tTERM\tCRN\tINSTRUCTOR\tRESPONDED\tRATING\tPRIOR\tADJUSTED")
+	for _, s := range ranked {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%d\t%.2f\t%.2f\t%.2f\n",
+			s.Rank, s.Name, s.Term, s.Crn, s.Instructor, s.Responded, s.Rating, s.PriorRating, s.AdjustedRating)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+func TestRankSections(t *testing.T) {
+	// A large section rated 4.5, a tiny one rated 5, and a small one rated 1
+	// in another course
+	large := scrapetest.Isq(scrapetest.Course("COP2220", 1, "Smith"), 45, 40, [5]float64{50, 50, 0, 0, 0})
+	tiny := scrapetest.Isq(scrapetest.Course("COP2220", 2, "Doe"), 40, 2, [5]float64{100, 0, 0, 0, 0})
+	other := scrapetest.Isq(scrapetest.Course("COT3100", 3, "Liu"), 12, 10, [5]float64{0, 0, 0, 0, 100})
+
+	tests := []struct {
+		name        string
+		isqs        []scrape.CourseIsq
+		level       PriorLevel
+		priorWeight float64
+		want        []RankedSection
+	}{
+		{
+			// The prior is the course's 190 points over 42 responses, weighted
+			// by its 21 responses per section
+			name:  "course prior",
+			isqs:  []scrape.CourseIsq{tiny, large},
+			level: PriorCourse,
+			want: []RankedSection{
+				{Rank: 1, CsvCourse: toCsvCourse(tiny.Course), Responded: 2, Rating: 5,
+					Adjusted: Adjusted{PriorRating: 4.52, AdjustedRating: 4.57}},
+				{Rank: 2, CsvCourse: toCsvCourse(large.Course), Responded: 40, Rating: 4.5,
+					Adjusted: Adjusted{PriorRating: 4.52, AdjustedRating: 4.51}},
+			},
+		},
+		{
+			name:        "prior weight",
+			isqs:        []scrape.CourseIsq{tiny, large},
+			level:       PriorCourse,
+			priorWeight: 10,
+			want: []RankedSection{
+				{Rank: 1, CsvCourse: toCsvCourse(tiny.Course), Responded: 2, Rating: 5,
+					Adjusted: Adjusted{PriorRating: 4.52, AdjustedRating: 4.6}},
+				{Rank: 2, CsvCourse: toCsvCourse(large.Course), Responded: 40, Rating: 4.5,
+					Adjusted: Adjusted{PriorRating: 4.52, AdjustedRating: 4.5}},
+			},
+		},
+		{
+			// The tiny section is pulled below the large one by the low
+			// rating of the other course
+			name:  "all prior",
+			isqs:  []scrape.CourseIsq{tiny, large, other},
+			level: PriorAll,
+			want: []RankedSection{
+				{Rank: 1, CsvCourse: toCsvCourse(large.Course), Responded: 40, Rating: 4.5,
+					Adjusted: Adjusted{PriorRating: 3.85, AdjustedRating: 4.3}},
+				{Rank: 2, CsvCourse: toCsvCourse(tiny.Course), Responded: 2, Rating: 5,
+					Adjusted: Adjusted{PriorRating: 3.85, AdjustedRating: 3.97}},
+				{Rank: 3, CsvCourse: toCsvCourse(other.Course), Responded: 10, Rating: 1,
+					Adjusted: Adjusted{PriorRating: 3.85, AdjustedRating: 2.8}},
+			},
+		},
+		{
+			name:  "no responses",
+			isqs:  []scrape.CourseIsq{scrapetest.Isq(scrapetest.Course("COP2220", 4, "Doe"), 20, 0, [5]float64{})},
+			level: PriorCourse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RankSections(CourseInput{Isqs: tt.isqs}, tt.level, tt.priorWeight)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RankSections() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// SectionChange is one change to a section of a department's schedule, such
+// as a new instructor or room, or the section being added or removed
+type SectionChange struct {
+	CsvCourse
+	ChangedAt time.Time `csv:"changed_at" json:"changed_at"`
+	Change    string    `csv:"change" json:"change"`
+	Before    string    `csv:"before" json:"before"`
+	After     string    `csv:"after" json:"after"`
+}
+
+// sectionFields are the parts of a section compared between its versions
+var sectionFields = []struct {
+	name  string
+	value func(scrape.DeptSchedule) string
+}{
+	{"status", func(d scrape.DeptSchedule) string { return parseNullString(d.Status) }},
+	{"title", func(d scrape.DeptSchedule) string { return d.Title }},
+	{"instructor", func(d scrape.DeptSchedule) string { return parseNullString(d.Instructor) }},
+	{"credits", func(d scrape.DeptSchedule) string { return strconv.Itoa(d.Credits) }},
+	{"part_of_term", func(d scrape.DeptSchedule) string { return d.PartOfTerm }},
+	{"meetings", meetingTimes},
+	{"room", meetingRooms},
+	{"campus", func(d scrape.DeptSchedule) string { return d.Campus }},
+	{"approval", func(d scrape.DeptSchedule) string { return parseNullString(d.Approval) }},
+}
+
+// DescribeChanges lists the changes between the versions of a department's
+// sections made at or after since, oldest first. The versions first
+// recorded for a term are where its history starts, so they aren't listed
+// as added.
+func DescribeChanges(versions []scrape.ScheduleVersion, since time.Time) []SectionChange {
+	baselines := make(map[scrape.Term]time.Time)
+	sections := make(map[sectionKey][]scrape.ScheduleVersion)
+	var keys []sectionKey
+	for _, v := range versions {
+		if baseline, found := baselines[v.Term]; !found || v.ValidFrom.Before(baseline) {
+			baselines[v.Term] = v.ValidFrom
+		}
+		key := toSectionKey(v.Course)
+		if _, found := sections[key]; !found {
+			keys = append(keys, key)
+		}
+		sections[key] = append(sections[key], v)
+	}
+
+	var changes []SectionChange
+	for _, key := range keys {
+		history := sections[key]
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].ValidFrom.Before(history[j].ValidFrom)
+		})
+
+		first := history[0]
+		if first.ValidFrom.After(baselines[key.Term]) && !first.ValidFrom.Before(since) {
+			changes = append(changes, SectionChange{
+				CsvCourse: toCsvCourse(first.Course),
+				ChangedAt: first.ValidFrom,
+				Change:    "added",
+				After:     first.Title,
+			})
+		}
+		for i := 1; i < len(history); i++ {
+			before, after := history[i-1], history[i]
+			if after.ValidFrom.Before(since) {
+				continue
+			}
+			if before.ValidTo.Valid && before.ValidTo.Timestamp.Before(after.ValidFrom) {
+				// The section was removed and later added back
+				if !before.ValidTo.Timestamp.Before(since) {
+					changes = append(changes, SectionChange{
+						CsvCourse: toCsvCourse(before.Course),
+						ChangedAt: before.ValidTo.Timestamp,
+						Change:    "removed",
+						Before:    before.Title,
+					})
+				}
+				changes = append(changes, SectionChange{
+					CsvCourse: toCsvCourse(after.Course),
+					ChangedAt: after.ValidFrom,
+					Change:    "added",
+					After:     after.Title,
+				})
+				continue
+			}
+			for _, field := range sectionFields {
+				a, b := field.value(before.DeptSchedule), field.value(after.DeptSchedule)
+				if a != b {
+					changes = append(changes, SectionChange{
+						CsvCourse: toCsvCourse(after.Course),
+						ChangedAt: after.ValidFrom,
+						Change:    field.name,
+						Before:    a,
+						After:     b,
+					})
+				}
+			}
+		}
+		last := history[len(history)-1]
+		if last.ValidTo.Valid && !last.ValidTo.Timestamp.Before(since) {
+			changes = append(changes, SectionChange{
+				CsvCourse: toCsvCourse(last.Course),
+				ChangedAt: last.ValidTo.Timestamp,
+				Change:    "removed",
+				Before:    last.Title,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].ChangedAt.Equal(changes[j].ChangedAt) {
+			return changes[i].ChangedAt.Before(changes[j].ChangedAt)
+		}
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Crn < changes[j].Crn
+	})
+	return changes
+}
+
+// WriteChangeTable prints the changes to a department's sections as an
+// aligned table
+func WriteChangeTable(w io.Writer, changes []SectionChange) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGED\tCOURSE\tCRN\tINSTRUCTOR\tCHANGE\tBEFORE\tAFTER")
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", c.ChangedAt.Local().Format("2006-01-02 15:04"),
+			c.Name, c.Crn, c.Instructor, c.Change, c.Before, c.After)
+	}
+	return tw.Flush()
+}
+
+// meetingTimes describes when a section meets, like "LEC MWF 10:00-10:50"
+func meetingTimes(d scrape.DeptSchedule) string {
+	var times []string
+	for _, m := range d.Meetings {
+		t := strings.TrimSpace(m.Type + " " + parseNullString(m.Days))
+		if m.BeginTime.Valid {
+			t += " " + clockTime(m.BeginTime) + "-" + clockTime(m.EndTime)
+		}
+		times = append(times, t)
+	}
+	return strings.Join(times, "; ")
+}
+
+// meetingRooms describes where a section meets, like "15 1200"
+func meetingRooms(d scrape.DeptSchedule) string {
+	var rooms []string
+	for _, m := range d.Meetings {
+		rooms = append(rooms, strings.TrimSpace(parseNullString(m.Building)+" "+parseNullInt64(m.Room)))
+	}
+	return strings.Join(rooms, "; ")
+}
+
+func clockTime(t bigquery.NullTime) string {
+	return fmt.Sprintf("%02d:%02d", t.Time.Hour, t.Time.Minute)
+}
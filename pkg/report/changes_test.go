@@ -0,0 +1,72 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+// version makes a version of a section of COP2220 meeting in a room, valid
+// from one time until another, or still current if to is zero
+func version(c scrape.Course, room int64, from, to time.Time) scrape.ScheduleVersion {
+	meeting := scrapetest.Meeting("MW", 1030, 1145, room, scrapetest.TermStart, scrapetest.TermEnd)
+	return scrapetest.Version(scrapetest.Section(c, meeting), from, to)
+}
+
+func TestDescribeChanges(t *testing.T) {
+	t0 := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	t1, t2, t3 := t0.Add(24*time.Hour), t0.Add(48*time.Hour), t0.Add(72*time.Hour)
+	moved, removed := scrapetest.Course("COP2220", 1, "Liu"), scrapetest.Course("COP2220", 2, "Smith")
+	added, readded := scrapetest.Course("COP2220", 3, "Doe"), scrapetest.Course("COP2220", 4, "Roy")
+
+	// The first sync at t0 is where the history starts, so nothing is added
+	// then
+	versions := []scrape.ScheduleVersion{
+		version(moved, 1200, t0, t1),
+		version(moved, 1400, t1, time.Time{}),
+		version(removed, 1200, t0, t2),
+		version(added, 1300, t1, time.Time{}),
+		version(readded, 1300, t0, t1),
+		version(readded, 1300, t3, time.Time{}),
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []SectionChange
+	}{
+		{
+			name: "every change",
+			want: []SectionChange{
+				{CsvCourse: toCsvCourse(moved), ChangedAt: t1, Change: "room", Before: "15 1200", After: "15 1400"},
+				{CsvCourse: toCsvCourse(added), ChangedAt: t1, Change: "added", After: "Computer Science I"},
+				{CsvCourse: toCsvCourse(readded), ChangedAt: t1, Change: "removed", Before: "Computer Science I"},
+				{CsvCourse: toCsvCourse(removed), ChangedAt: t2, Change: "removed", Before: "Computer Science I"},
+				{CsvCourse: toCsvCourse(readded), ChangedAt: t3, Change: "added", After: "Computer Science I"},
+			},
+		},
+		{
+			name:  "since",
+			since: t2,
+			want: []SectionChange{
+				{CsvCourse: toCsvCourse(removed), ChangedAt: t2, Change: "removed", Before: "Computer Science I"},
+				{CsvCourse: toCsvCourse(readded), ChangedAt: t3, Change: "added", After: "Computer Science I"},
+			},
+		},
+		{
+			name:  "none since",
+			since: t3.Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DescribeChanges(versions, tt.since)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DescribeChanges() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
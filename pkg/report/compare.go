@@ -0,0 +1,162 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// z-score of a two-sided 95% confidence interval
+const z95 = 1.96
+
+type InstructorSummary struct {
+	Instructor string      `csv:"instructor" json:"instructor"`
+	Sections   int         `csv:"sections" json:"sections"`
+	Enrolled   int         `csv:"enrolled" json:"enrolled"`
+	Rating     float64     `csv:"rating" json:"rating"`
+	RatingLow  float64     `csv:"rating_ci_low" json:"rating_ci_low"`
+	RatingHigh float64     `csv:"rating_ci_high" json:"rating_ci_high"`
+	AverageGpa float64     `csv:"average_gpa" json:"average_gpa"`
+	DFRate     float64     `csv:"df_rate" json:"df_rate"`
+	FirstTerm  scrape.Term `csv:"first_term" json:"first_term"`
+	LastTerm   scrape.Term `csv:"last_term" json:"last_term"`
+}
+
+// instructorTotals accumulates the sections taught by one instructor
+type instructorTotals struct {
+	sections            int
+	enrolled            int
+	ratingSum           float64
+	ratingWeight        float64
+	ratingVarWeight     float64 // sum of weight^2 / responses, see summarize
+	gpaSum              float64
+	dfSum               float64
+	gradesWeight        float64
+	responses           [5]float64 // number of responses rated 1 through 5
+	firstTerm, lastTerm scrape.Term
+}
+
+// CompareInstructors aggregates the sections of a course by instructor. The
+// rating is weighted by enrollment, and its confidence interval is centered
+// on it with the spread of the pooled response distribution. The GPA and D/F
+// rate come from every section with grades, ISQ or not, each counting the
+// same since the grade distribution doesn't say how many students it covers.
+func CompareInstructors(r CourseInput) []InstructorSummary {
+	r.FullJoin = true // sections with grades but no ISQ still have a GPA
+	totals := make(map[string]*instructorTotals)
+	var instructors []string
+	for _, row := range joinCourse(r) {
+		if row.Isq == nil && row.Grades == nil {
+			continue // only scheduled, there's nothing to compare
+		}
+		t, found := totals[row.CsvCourse.Instructor]
+		if !found {
+			t = &instructorTotals{}
+			totals[row.CsvCourse.Instructor] = t
+			instructors = append(instructors, row.CsvCourse.Instructor)
+		}
+		t.add(row)
+	}
+
+	summaries := make([]InstructorSummary, 0, len(instructors))
+	for _, instructor := range instructors {
+		summaries = append(summaries, totals[instructor].summarize(instructor))
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Rating > summaries[j].Rating
+	})
+	return summaries
+}
+
+func (t *instructorTotals) add(row CourseRow) {
+	t.sections++
+	if term := row.CsvCourse.Term; term.Valid() {
+		if t.firstTerm == "" || term.Before(t.firstTerm) {
+			t.firstTerm = term
+		}
+		if t.lastTerm == "" || t.lastTerm.Before(term) {
+			t.lastTerm = term
+		}
+	}
+
+	if row.Grades != nil {
+		t.gpaSum += row.Average
+		t.dfSum += row.PercentD + row.PercentF
+		t.gradesWeight++
+	}
+
+	if row.Isq == nil {
+		return // enrollment is only known from the ISQ
+	}
+	t.enrolled += row.Enrolled
+	if weight := float64(row.Enrolled); row.Responded > 0 && weight > 0 {
+		t.ratingSum += row.Rating * weight
+		t.ratingWeight += weight
+		t.ratingVarWeight += weight * weight / float64(row.Responded)
+		percents := [5]float64{row.Percent1, row.Percent2, row.Percent3, row.Percent4, row.Percent5}
+		for i, percent := range percents {
+			t.responses[i] += percent / 100 * float64(row.Responded)
+		}
+	}
+}
+
+func (t *instructorTotals) summarize(instructor string) InstructorSummary {
+	s := InstructorSummary{
+		Instructor: instructor,
+		Sections:   t.sections,
+		Enrolled:   t.enrolled,
+		FirstTerm:  t.firstTerm,
+		LastTerm:   t.lastTerm,
+		RatingLow:  1,
+		RatingHigh: 5,
+	}
+	if t.ratingWeight > 0 {
+		s.Rating = round2(t.ratingSum / t.ratingWeight)
+	}
+	if t.gradesWeight > 0 {
+		s.AverageGpa = round2(t.gpaSum / t.gradesWeight)
+		s.DFRate = round2(t.dfSum / t.gradesWeight)
+	}
+
+	// With fewer than two responses the rating could be anything on the scale.
+	// Each section's mean rating varies by the pooled variance over its
+	// responses, so the weighted rating varies by the pooled variance times
+	// the sum of weight^2 / responses over the total weight squared.
+	var n, sum float64
+	for i, count := range t.responses {
+		n += count
+		sum += float64(i+1) * count
+	}
+	if n >= 2 && t.ratingWeight > 0 {
+		mean := sum / n
+		var squares float64
+		for i, count := range t.responses {
+			squares += count * math.Pow(float64(i+1)-mean, 2)
+		}
+		rating := t.ratingSum / t.ratingWeight
+		margin := z95 * math.Sqrt(squares/(n-1)*t.ratingVarWeight) / t.ratingWeight
+		s.RatingLow = round2(math.Max(1, rating-margin))
+		s.RatingHigh = round2(math.Min(5, rating+margin))
+	}
+	return s
+}
+
+// WriteInstructorTable prints the instructor comparison as an aligned table
+func WriteInstructorTable(w io.Writer, summaries []InstructorSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTRUCTOR\tSECTIONS\tENROLLED\tRATING\t95% CI\tGPA\tD/F %\tFIRST TERM\tLAST TERM")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f-%.2f\t%.2f\t%.2f\t%s\t%s\n",
+			s.Instructor, s.Sections, s.Enrolled, s.Rating, s.RatingLow, s.RatingHigh,
+			s.AverageGpa, s.DFRate, s.FirstTerm, s.LastTerm)
+	}
+	return tw.Flush()
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
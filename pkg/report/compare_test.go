@@ -0,0 +1,72 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+func TestCompareInstructors(t *testing.T) {
+	smith1 := scrapetest.Course("COP2220", 10001, "Smith")
+	smith2 := scrapetest.Course("COP2220", 10002, "Smith")
+	smith3 := scrapetest.Course("COP2220", 10003, "Smith")
+	jones := scrapetest.Course("COP2220", 10004, "Jones")
+
+	tests := []struct {
+		name string
+		in   CourseInput
+		want []InstructorSummary
+	}{
+		{
+			name: "grades without isq",
+			in: CourseInput{
+				Isqs: []scrape.CourseIsq{
+					scrapetest.Isq(smith1, 20, 10, [5]float64{50, 50, 0, 0, 0}),
+					scrapetest.Isq(smith2, 20, 5, [5]float64{0, 100, 0, 0, 0}),
+				},
+				Grades: []scrape.CourseGrades{
+					scrapetest.Grades(smith1, 3, 5, 5),
+					scrapetest.Grades(smith3, 2, 10, 10),
+				},
+			},
+			want: []InstructorSummary{
+				{Instructor: "Smith", Sections: 3, Enrolled: 40, Rating: 4.25, RatingLow: 3.99, RatingHigh: 4.51,
+					AverageGpa: 2.5, DFRate: 15, FirstTerm: scrapetest.Term, LastTerm: scrapetest.Term},
+			},
+		},
+		{
+			name: "responses without enrollment",
+			in: CourseInput{
+				Isqs: []scrape.CourseIsq{
+					scrapetest.Isq(smith1, 20, 10, [5]float64{50, 50, 0, 0, 0}),
+					scrapetest.Isq(jones, 0, 3, [5]float64{100, 0, 0, 0, 0}),
+				},
+			},
+			want: []InstructorSummary{
+				{Instructor: "Smith", Sections: 1, Enrolled: 20, Rating: 4.5, RatingLow: 4.17, RatingHigh: 4.83,
+					FirstTerm: scrapetest.Term, LastTerm: scrapetest.Term},
+				{Instructor: "Jones", Sections: 1, RatingLow: 1, RatingHigh: 5,
+					FirstTerm: scrapetest.Term, LastTerm: scrapetest.Term},
+			},
+		},
+		{
+			name: "single response",
+			in: CourseInput{
+				Isqs: []scrape.CourseIsq{scrapetest.Isq(jones, 12, 1, [5]float64{0, 0, 100, 0, 0})},
+			},
+			want: []InstructorSummary{
+				{Instructor: "Jones", Sections: 1, Enrolled: 12, Rating: 3, RatingLow: 1, RatingHigh: 5,
+					FirstTerm: scrapetest.Term, LastTerm: scrapetest.Term},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareInstructors(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareInstructors() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
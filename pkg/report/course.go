@@ -2,52 +2,106 @@ package report
 
 import (
 	"github.com/openswoop/isqool/pkg/scrape"
+	"io"
 	"sort"
 )
 
-type courseView struct {
+// CourseRow is one section in the course report. The data a section is
+// missing is left nil so it's written as empty cells rather than zeros.
+type CourseRow struct {
 	CsvCourse
-	scrape.Isq
-	scrape.Grades
-	scrape.Schedule
+	Coverage
+	*scrape.Isq
+	*Adjusted
+	*scrape.Grades
+	*scrape.Schedule
+}
+
+// Coverage records which sources a section was found in
+type Coverage struct {
+	HasIsq      bool `csv:"has_isq" json:"has_isq"`
+	HasGrades   bool `csv:"has_grades" json:"has_grades"`
+	HasSchedule bool `csv:"has_schedule" json:"has_schedule"`
 }
 
 type CourseInput struct {
 	Isqs      []scrape.CourseIsq
 	Grades    []scrape.CourseGrades
 	Schedules []scrape.CourseSchedule
+
+	// FullJoin includes sections without an ISQ, such as those whose
+	// evaluations were suppressed for low response
+	FullJoin bool
+}
+
+// WriteCourse writes the course report to fileName, or stdout if it's "-"
+func WriteCourse(fileName string, format Format, r CourseInput) error {
+	return WriteFormat(BuildCourseRows(r), format, fileName)
+}
+
+// WriteCourseTo writes the course report to w
+func WriteCourseTo(w io.Writer, format Format, r CourseInput) error {
+	return Encode(w, BuildCourseRows(r), format)
+}
+
+// BuildCourseRows joins the ISQs, grades, and schedules of a course into one
+// row per section, most recent term first
+func BuildCourseRows(r CourseInput) []CourseRow {
+	rows := joinCourse(r)
+	adjustRatings(rows, PriorCourse, 0)
+	sort.Sort(sort.Reverse(rows))
+	return rows
 }
 
-func WriteCourse(name string, r CourseInput) error {
-	courseToIsq := make(map[scrape.Course]scrape.Isq, len(r.Isqs))
-	for _, v := range r.Isqs {
-		courseToIsq[v.Course] = v.Isq
+// joinCourse left joins the grades and schedules of each section to its ISQ,
+// or full outer joins all three if requested, after reconciling their keys
+func joinCourse(r CourseInput) courseReport {
+	r, _ = Reconcile(r)
+
+	var courses []scrape.Course
+	seen := make(map[scrape.Course]bool)
+	addCourse := func(c scrape.Course) {
+		if !seen[c] {
+			courses = append(courses, c)
+			seen[c] = true
+		}
+	}
+
+	courseToIsq := make(map[scrape.Course]*scrape.Isq, len(r.Isqs))
+	for i, v := range r.Isqs {
+		courseToIsq[v.Course] = &r.Isqs[i].Isq
+		addCourse(v.Course)
 	}
-	courseToGrades := make(map[scrape.Course]scrape.Grades, len(r.Isqs))
-	for _, v := range r.Grades {
-		courseToGrades[v.Course] = v.Grades
+	courseToGrades := make(map[scrape.Course]*scrape.Grades, len(r.Grades))
+	for i, v := range r.Grades {
+		courseToGrades[v.Course] = &r.Grades[i].Grades
+		if r.FullJoin {
+			addCourse(v.Course)
+		}
 	}
-	courseToSchedules := make(map[scrape.Course]scrape.Schedule, len(r.Isqs))
-	for _, v := range r.Schedules {
-		courseToSchedules[v.Course] = v.Schedule
+	courseToSchedules := make(map[scrape.Course]*scrape.Schedule, len(r.Schedules))
+	for i, v := range r.Schedules {
+		courseToSchedules[v.Course] = &r.Schedules[i].Schedule
+		if r.FullJoin {
+			addCourse(v.Course)
+		}
 	}
 
-	// Left join grades and schedules to isqs
 	var rows courseReport
-	for course, isq := range courseToIsq {
-		rows = append(rows, courseView{
+	for _, course := range courses {
+		row := CourseRow{
 			CsvCourse: toCsvCourse(course),
-			Isq:       isq,
+			Isq:       courseToIsq[course],
 			Grades:    courseToGrades[course],
 			Schedule:  courseToSchedules[course],
-		})
+		}
+		row.Coverage = Coverage{row.Isq != nil, row.Grades != nil, row.Schedule != nil}
+		rows = append(rows, row)
 	}
-
-	sort.Sort(sort.Reverse(rows))
-	return WriteCsv(rows, name+".csv")
+	return rows
 }
 
-type courseReport []courseView
+type courseReport []CourseRow
 
 func (r courseReport) Len() int {
 	return len(r)
@@ -58,7 +112,5 @@ func (r courseReport) Swap(i, j int) {
 }
 
 func (r courseReport) Less(i, j int) bool {
-	aTerm, _ := scrape.TermToId(r[i].CsvCourse.Term)
-	bTerm, _ := scrape.TermToId(r[j].CsvCourse.Term)
-	return aTerm < bTerm
-}
\ No newline at end of file
+	return r[i].CsvCourse.Term.Before(r[j].CsvCourse.Term)
+}
@@ -4,10 +4,13 @@ import (
 	"cloud.google.com/go/bigquery"
 	"fmt"
 	"github.com/openswoop/isqool/pkg/scrape"
+	"io"
 	"strconv"
 )
 
-type departmentViewFull struct {
+// DepartmentRow is one meeting in the department report. Only the first
+// meeting of a section has the section's details filled in.
+type DepartmentRow struct {
 	Status  string `csv:"status"`
 	Crn     string `csv:"crn"`
 	Course  string `csv:"course"`
@@ -32,8 +35,19 @@ type DepartmentViewPartial struct {
 	Campus     string `csv:"campus"`
 }
 
-func WriteDepartment(name string, d []scrape.DeptSchedule) error {
-	var rows []departmentViewFull
+// WriteDepartment writes the department report to fileName, or stdout if it's "-"
+func WriteDepartment(fileName string, format Format, d []scrape.DeptSchedule) error {
+	return WriteFormat(BuildDepartmentRows(d), format, fileName)
+}
+
+// WriteDepartmentTo writes the department report to w
+func WriteDepartmentTo(w io.Writer, format Format, d []scrape.DeptSchedule) error {
+	return Encode(w, BuildDepartmentRows(d), format)
+}
+
+// BuildDepartmentRows flattens a department's schedules into one row per meeting
+func BuildDepartmentRows(d []scrape.DeptSchedule) []DepartmentRow {
+	var rows []DepartmentRow
 	for _, course := range d {
 		for i, meeting := range course.Meetings {
 			isContinuationRow := i > 0
@@ -52,7 +66,7 @@ func WriteDepartment(name string, d []scrape.DeptSchedule) error {
 			}
 
 			if !isContinuationRow {
-				rows = append(rows, departmentViewFull{
+				rows = append(rows, DepartmentRow{
 					Status:                parseNullString(course.Status),
 					Crn:                   strconv.Itoa(course.Crn),
 					Course:                course.Name,
@@ -64,14 +78,14 @@ func WriteDepartment(name string, d []scrape.DeptSchedule) error {
 					Instructor:            parseNullString(course.Instructor),
 				})
 			} else {
-				rows = append(rows, departmentViewFull{
+				rows = append(rows, DepartmentRow{
 					DepartmentViewPartial: partial,
 				})
 			}
 		}
 	}
 
-	return WriteCsv(rows, name+".csv")
+	return rows
 }
 
 func parseNullString(n bigquery.NullString) string {
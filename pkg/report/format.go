@@ -0,0 +1,397 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/gocarina/gocsv"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is an output format that report rows can be written in
+type Format string
+
+const (
+	Csv      Format = "csv"
+	Json     Format = "json"
+	Ndjson   Format = "ndjson"
+	Parquet  Format = "parquet"
+	Xlsx     Format = "xlsx"
+	Markdown Format = "markdown"
+)
+
+var Formats = []Format{Csv, Json, Ndjson, Parquet, Xlsx, Markdown}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	if strings.ToLower(s) == "md" {
+		return Markdown, nil
+	}
+	return "", fmt.Errorf("%s is not a valid format (%s)", s, FormatNames())
+}
+
+// FormatNames lists the valid formats, for use in flag descriptions
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Extension is the file extension for the format, including the dot
+func (f Format) Extension() string {
+	if f == Markdown {
+		return ".md"
+	}
+	return "." + string(f)
+}
+
+// ContentType is the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case Csv:
+		return "text/csv; charset=utf-8"
+	case Json:
+		return "application/json"
+	case Ndjson:
+		return "application/x-ndjson"
+	case Parquet:
+		return "application/vnd.apache.parquet"
+	case Xlsx:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case Markdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// OutputName is where a report named name is written to: output if it was
+// given, otherwise name with the format's extension
+func OutputName(name string, format Format, output string) string {
+	if output != "" {
+		return output
+	}
+	return name + format.Extension()
+}
+
+// WriteFormat writes rows, a slice of structs, to fileName in the given
+// format. Columns are named by the rows' csv tags in every format, and
+// embedded structs are flattened. A fileName of "-" writes to stdout.
+func WriteFormat(rows interface{}, format Format, fileName string) error {
+	if fileName == "-" {
+		return Encode(os.Stdout, rows, format)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := Encode(file, rows, format); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Encode writes rows, a slice of structs, to w in the given format
+func Encode(w io.Writer, rows interface{}, format Format) error {
+	switch format {
+	case Csv:
+		return gocsv.Marshal(rows, w)
+	case Markdown:
+		return encodeMarkdown(w, rows)
+	}
+
+	columns, records, err := flatten(rows)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case Json:
+		return encodeJson(w, columns, records)
+	case Ndjson:
+		return encodeNdjson(w, columns, records)
+	case Parquet:
+		return encodeParquet(w, columns, records)
+	case Xlsx:
+		return encodeXlsx(w, columns, records)
+	default:
+		return fmt.Errorf("%s is not a valid format (%s)", format, FormatNames())
+	}
+}
+
+// column is a flattened struct field with a value of type String, Int64,
+// Float64, or Bool
+type column struct {
+	name string
+	kind reflect.Kind
+}
+
+// flatten turns a slice of structs into columns and typed records, with nil
+// standing in for missing values
+func flatten(rows interface{}) ([]column, [][]interface{}, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("%T is not a slice", rows)
+	}
+	t := v.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%T is not a slice of structs", rows)
+	}
+
+	var columns []column
+	var indexes [][]int
+	flattenType(t, nil, &columns, &indexes)
+
+	records := make([][]interface{}, v.Len())
+	for i := range records {
+		row := reflect.Indirect(v.Index(i))
+		record := make([]interface{}, len(columns))
+		for j, index := range indexes {
+			record[j] = fieldValue(row, index)
+		}
+		records[i] = record
+	}
+	return columns, records, nil
+}
+
+func flattenType(t reflect.Type, parent []int, columns *[]column, indexes *[][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag := strings.Split(field.Tag.Get("csv"), ",")[0]
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && embedded.Kind() == reflect.Struct && tag == "" {
+			flattenType(embedded, index, columns, indexes)
+			continue
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		*columns = append(*columns, column{tag, columnKind(field.Type)})
+		*indexes = append(*indexes, index)
+	}
+}
+
+func columnKind(t reflect.Type) reflect.Kind {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeOf(bigquery.NullInt64{}):
+		return reflect.Int64
+	case reflect.TypeOf(bigquery.NullFloat64{}):
+		return reflect.Float64
+	case reflect.TypeOf(bigquery.NullBool{}):
+		return reflect.Bool
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int64
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.Bool:
+		return reflect.Bool
+	default:
+		return reflect.String
+	}
+}
+
+// fieldValue returns the value of the field at index, stopping at nil
+// pointers along the way
+func fieldValue(v reflect.Value, index []int) interface{} {
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case bigquery.NullString:
+		if !value.Valid {
+			return nil
+		}
+		return value.StringVal
+	case bigquery.NullInt64:
+		if !value.Valid {
+			return nil
+		}
+		return value.Int64
+	case bigquery.NullFloat64:
+		if !value.Valid {
+			return nil
+		}
+		return value.Float64
+	case bigquery.NullBool:
+		if !value.Valid {
+			return nil
+		}
+		return value.Bool
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return value.String()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// marshalRecord encodes a record as a JSON object with its keys in column order
+func marshalRecord(columns []column, record []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, c := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(c.name)
+		value, err := json.Marshal(record[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func encodeJson(w io.Writer, columns []column, records [][]interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, record := range records {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		b, err := marshalRecord(columns, record)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n  ")
+		buf.Write(b)
+	}
+	buf.WriteString("\n]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func encodeNdjson(w io.Writer, columns []column, records [][]interface{}) error {
+	for _, record := range records {
+		b, err := marshalRecord(columns, record)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeParquet builds a struct type with one optional field per column so
+// the schema keeps the columns in order
+func encodeParquet(w io.Writer, columns []column, records [][]interface{}) error {
+	fields := make([]reflect.StructField, len(columns))
+	for i, c := range columns {
+		var t reflect.Type
+		switch c.kind {
+		case reflect.Int64:
+			t = reflect.TypeOf(int64(0))
+		case reflect.Float64:
+			t = reflect.TypeOf(float64(0))
+		case reflect.Bool:
+			t = reflect.TypeOf(false)
+		default:
+			t = reflect.TypeOf("")
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("Column%d", i),
+			Type: reflect.PtrTo(t),
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"%s,optional"`, c.name)),
+		}
+	}
+	rowType := reflect.StructOf(fields)
+
+	writer := parquet.NewWriter(w, parquet.SchemaOf(reflect.New(rowType).Elem().Interface()))
+	for _, record := range records {
+		row := reflect.New(rowType).Elem()
+		for i, value := range record {
+			if value == nil {
+				continue
+			}
+			ptr := reflect.New(fields[i].Type.Elem())
+			ptr.Elem().Set(reflect.ValueOf(value).Convert(fields[i].Type.Elem()))
+			row.Field(i).Set(ptr)
+		}
+		if err := writer.Write(row.Interface()); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// encodeMarkdown writes the same columns as the CSV output as a Markdown table
+func encodeMarkdown(w io.Writer, rows interface{}) error {
+	b, err := gocsv.MarshalBytes(rows)
+	if err != nil {
+		return err
+	}
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for i, record := range records {
+		for j := range record {
+			record[j] = strings.ReplaceAll(record[j], "|", "\\|")
+		}
+		sb.WriteString("| " + strings.Join(record, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString(strings.Repeat("| --- ", len(record)) + "|\n")
+		}
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
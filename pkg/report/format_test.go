@@ -0,0 +1,221 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type FormatDetail struct {
+	Rating float64 `csv:"rating"`
+	Open   bool    `csv:"open"`
+}
+
+type formatRow struct {
+	Name  string `csv:"name"`
+	Count int    `csv:"count"`
+	*FormatDetail
+}
+
+// formatRows are written in every format and read back as formatRecords,
+// by column with missing values empty
+var formatRows = []formatRow{
+	{Name: "Smith, John", Count: 40, FormatDetail: &FormatDetail{Rating: 4.5, Open: true}},
+	{Name: `A|B "C"`, Count: 0},
+}
+var formatRecords = []map[string]string{
+	{"name": "Smith, John", "count": "40", "rating": "4.5", "open": "true"},
+	{"name": `A|B "C"`, "count": "0", "rating": "", "open": ""},
+}
+
+// decoders read back what Encode wrote in each format
+var decoders = map[Format]func([]byte) ([]map[string]string, error){
+	Csv:      decodeCsv,
+	Json:     decodeJson,
+	Ndjson:   decodeNdjson,
+	Parquet:  decodeParquet,
+	Xlsx:     decodeXlsx,
+	Markdown: decodeMarkdown,
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, formatRows, format); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := decoders[format](buf.Bytes())
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			if !reflect.DeepEqual(got, formatRecords) {
+				t.Errorf("round trip = %v, want %v", got, formatRecords)
+			}
+		})
+	}
+}
+
+// toRecords pairs each row of a table with the header row
+func toRecords(table [][]string) []map[string]string {
+	var records []map[string]string
+	for _, row := range table[1:] {
+		record := make(map[string]string)
+		for i, name := range table[0] {
+			record[name] = row[i]
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// toString formats a decoded value, or nil as empty
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func decodeCsv(b []byte) ([]map[string]string, error) {
+	table, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return toRecords(table), nil
+}
+
+func decodeJson(b []byte) ([]map[string]string, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(b, &objects); err != nil {
+		return nil, err
+	}
+	return fromObjects(objects), nil
+}
+
+func decodeNdjson(b []byte) ([]map[string]string, error) {
+	var objects []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return fromObjects(objects), nil
+}
+
+func fromObjects(objects []map[string]interface{}) []map[string]string {
+	records := make([]map[string]string, len(objects))
+	for i, object := range objects {
+		records[i] = make(map[string]string)
+		for k, v := range object {
+			records[i][k] = toString(v)
+		}
+	}
+	return records
+}
+
+func decodeParquet(b []byte) ([]map[string]string, error) {
+	f, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	columns := f.Schema().Columns()
+	rows := make([]parquet.Row, f.NumRows())
+	if _, err := parquet.NewReader(f).ReadRows(rows); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	objects := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		objects[i] = make(map[string]interface{})
+		for _, v := range row {
+			var value interface{}
+			switch {
+			case v.IsNull():
+			case v.Kind() == parquet.ByteArray:
+				value = string(v.ByteArray())
+			case v.Kind() == parquet.Int64:
+				value = v.Int64()
+			case v.Kind() == parquet.Double:
+				value = v.Double()
+			case v.Kind() == parquet.Boolean:
+				value = v.Boolean()
+			}
+			objects[i][columns[v.Column()][0]] = value
+		}
+	}
+	return fromObjects(objects), nil
+}
+
+func decodeXlsx(b []byte) ([]map[string]string, error) {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	f, err := z.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var worksheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &worksheet); err != nil {
+		return nil, err
+	}
+	var table [][]string
+	for _, row := range worksheet.Rows {
+		values := make([]string, 4)
+		for _, c := range row.Cells {
+			value := c.Value
+			switch c.Type {
+			case "inlineStr":
+				value = c.Inline
+			case "b":
+				value = map[string]string{"0": "false", "1": "true"}[c.Value]
+			}
+			values[c.Ref[0]-'A'] = value
+		}
+		table = append(table, values)
+	}
+	return toRecords(table), nil
+}
+
+func decodeMarkdown(b []byte) ([]map[string]string, error) {
+	var table [][]string
+	for i, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if i == 1 {
+			continue // the header's divider
+		}
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "| "), " |")
+		var row []string
+		for _, cell := range strings.Split(strings.ReplaceAll(line, `\|`, "\x00"), " | ") {
+			row = append(row, strings.ReplaceAll(cell, "\x00", "|"))
+		}
+		table = append(table, row)
+	}
+	return toRecords(table), nil
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+//go:embed templates/course.html
+var courseHtml string
+
+var courseTemplate = template.Must(template.New("course").Funcs(template.FuncMap{
+	"percent": func(f float64) template.CSS {
+		return template.CSS(fmt.Sprintf("%.2f%%", f))
+	},
+	"termId": func(term scrape.Term) int {
+		id, _ := term.Id()
+		return id
+	},
+}).Parse(courseHtml))
+
+// Dimensions of the GPA chart, in pixels
+const (
+	chartWidth   = 720
+	chartHeight  = 280
+	chartPadding = 40
+	chartBottom  = 70 // room for the rotated term labels
+	maxGpa       = 4.0
+)
+
+type courseHtmlData struct {
+	Name          string
+	Rows          []CourseRow
+	Instructors   []InstructorSummary
+	Distributions []CourseRow
+	Gpa           *gpaChart
+	Columns       []string
+	Records       [][]string
+}
+
+type gpaChart struct {
+	Width, Height       int
+	Left, Right, Bottom int
+	Line                string
+	Points              []chartPoint
+	YTicks              []chartTick
+}
+
+type chartPoint struct {
+	X, Y  float64
+	Term  scrape.Term
+	Label string
+}
+
+type chartTick struct {
+	Y     float64
+	Label string
+}
+
+// WriteCourseHtml writes the course report as a standalone HTML page to
+// fileName, or stdout if it's "-"
+func WriteCourseHtml(fileName string, name string, r CourseInput) error {
+	if fileName == "-" {
+		return WriteCourseHtmlTo(os.Stdout, name, r)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := WriteCourseHtmlTo(file, name, r); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteCourseHtmlTo writes the course report as a standalone HTML page with
+// the rating distribution of each section, the average GPA over time, the
+// instructor comparison, and a sortable table of the data. Everything is
+// inlined so the page works offline.
+func WriteCourseHtmlTo(w io.Writer, name string, r CourseInput) error {
+	rows := BuildCourseRows(r)
+	data := courseHtmlData{
+		Name:        name,
+		Rows:        rows,
+		Instructors: CompareInstructors(r),
+		Gpa:         newGpaChart(Trend(r, 1)),
+	}
+	for _, row := range rows {
+		if row.Isq != nil && row.Responded > 0 {
+			data.Distributions = append(data.Distributions, row)
+		}
+	}
+
+	columns, records, err := flatten(rows)
+	if err != nil {
+		return err
+	}
+	for _, c := range columns {
+		data.Columns = append(data.Columns, c.name)
+	}
+	for _, record := range records {
+		cells := make([]string, len(record))
+		for i, value := range record {
+			if value != nil {
+				cells[i] = fmt.Sprint(value)
+			}
+		}
+		data.Records = append(data.Records, cells)
+	}
+
+	return courseTemplate.Execute(w, data)
+}
+
+// newGpaChart plots the average GPA of each term that has grades
+func newGpaChart(trend TrendReport) *gpaChart {
+	var points []TermPoint
+	for _, p := range trend.Points {
+		if p.AverageGpa > 0 {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	chart := &gpaChart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartPadding,
+		Right:  chartWidth - chartPadding,
+		Bottom: chartHeight - chartBottom,
+	}
+	plotHeight := float64(chart.Bottom - chartPadding)
+	y := func(gpa float64) float64 {
+		return float64(chart.Bottom) - gpa/maxGpa*plotHeight
+	}
+	for gpa := 0.0; gpa <= maxGpa; gpa++ {
+		chart.YTicks = append(chart.YTicks, chartTick{y(gpa), fmt.Sprintf("%.1f", gpa)})
+	}
+
+	step := 0.0
+	if len(points) > 1 {
+		step = float64(chart.Right-chart.Left) / float64(len(points)-1)
+	}
+	var line []string
+	for i, p := range points {
+		point := chartPoint{
+			X:     float64(chart.Left) + step*float64(i),
+			Y:     y(p.AverageGpa),
+			Term:  p.Term,
+			Label: fmt.Sprintf("%s: %.2f", p.Term, p.AverageGpa),
+		}
+		chart.Points = append(chart.Points, point)
+		line = append(line, fmt.Sprintf("%.1f,%.1f", point.X, point.Y))
+	}
+	chart.Line = strings.Join(line, " ")
+	return chart
+}
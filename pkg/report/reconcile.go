@@ -0,0 +1,221 @@
+package report
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// Match describes how a row was matched to the rows of the other sources
+type Match string
+
+const (
+	// MatchExact rows have the same course, term, CRN, and instructor
+	MatchExact Match = "exact"
+	// MatchNormalized rows share an instructor's last name once normalized,
+	// e.g. "Smith" and "John Smith (P), Jane Doe"
+	MatchNormalized Match = "normalized"
+	// MatchSection rows only share the course, term, and CRN
+	MatchSection Match = "section"
+	// MatchNone rows weren't found in any other source
+	MatchNone Match = "unmatched"
+)
+
+// Reconciliation is a row that didn't match the other sources exactly
+type Reconciliation struct {
+	CsvCourse
+	Source            string `csv:"source" json:"source"`
+	MatchedInstructor string `csv:"matched_instructor" json:"matched_instructor"`
+	Match             Match  `csv:"match" json:"match"`
+}
+
+// sourceRow is a row of one of the sources and what it was matched to
+type sourceRow struct {
+	source    string
+	course    *scrape.Course
+	match     Match
+	matchedTo *scrape.Course
+}
+
+// Reconcile matches the ISQs, grades, and schedules of each section on its
+// course, term, and CRN, then on the normalized instructor names. Grades and
+// schedules are rekeyed to the ISQ they matched (or schedules to the grades
+// they matched, if the section has no ISQ) so they can be joined exactly. It
+// returns the rekeyed input and the rows that didn't match exactly.
+func Reconcile(r CourseInput) (CourseInput, []Reconciliation) {
+	r.Isqs = append([]scrape.CourseIsq(nil), r.Isqs...)
+	r.Grades = append([]scrape.CourseGrades(nil), r.Grades...)
+	r.Schedules = append([]scrape.CourseSchedule(nil), r.Schedules...)
+
+	// Group every row by its section and source
+	sections := make(map[sectionKey][][]*sourceRow)
+	var keys []sectionKey
+	add := func(i int, source string, course *scrape.Course) {
+		key := toSectionKey(*course)
+		if _, found := sections[key]; !found {
+			sections[key] = make([][]*sourceRow, 3)
+			keys = append(keys, key)
+		}
+		sections[key][i] = append(sections[key][i], &sourceRow{source: source, course: course, match: MatchNone})
+	}
+	for i := range r.Isqs {
+		add(0, "isq", &r.Isqs[i].Course)
+	}
+	for i := range r.Grades {
+		add(1, "grades", &r.Grades[i].Course)
+	}
+	for i := range r.Schedules {
+		add(2, "schedule", &r.Schedules[i].Course)
+	}
+
+	var report []Reconciliation
+	var matched []*sourceRow
+	for _, key := range keys {
+		// The first source present is the anchor the others are matched to
+		var anchors []*sourceRow
+		for _, rows := range sections[key] {
+			if len(rows) == 0 {
+				continue
+			}
+			if anchors == nil {
+				anchors = rows
+				continue
+			}
+			matchRows(anchors, rows)
+			matched = append(matched, rows...)
+		}
+
+		for _, rows := range sections[key] {
+			for _, row := range rows {
+				if row.match == MatchExact {
+					continue
+				}
+				rec := Reconciliation{
+					CsvCourse: toCsvCourse(*row.course),
+					Source:    row.source,
+					Match:     row.match,
+				}
+				if row.matchedTo != nil {
+					rec.MatchedInstructor = row.matchedTo.Instructor.StringVal
+				}
+				report = append(report, rec)
+			}
+		}
+	}
+
+	// Rekey the matched rows once they've all been reported
+	for _, row := range matched {
+		if row.matchedTo != nil {
+			*row.course = *row.matchedTo
+		}
+	}
+	return r, report
+}
+
+// matchRows matches the rows of one source to the anchors of the same
+// section, first on the exact instructor, then on the normalized instructor,
+// and finally on the section alone if only one of each is left. Anchors that
+// are matched by a row are marked as exact matches.
+func matchRows(anchors, rows []*sourceRow) {
+	claimed := make(map[*sourceRow]bool)
+	claim := func(row, anchor *sourceRow, match Match) {
+		row.match, row.matchedTo = match, anchor.course
+		claimed[anchor] = true
+		if anchor.match == MatchNone {
+			anchor.match = MatchExact
+		}
+	}
+
+	for _, row := range rows {
+		for _, anchor := range anchors {
+			if !claimed[anchor] && row.course.Instructor == anchor.course.Instructor {
+				claim(row, anchor, MatchExact)
+				break
+			}
+		}
+	}
+	for _, row := range rows {
+		if row.matchedTo != nil {
+			continue
+		}
+		for _, anchor := range anchors {
+			if !claimed[anchor] && sameInstructor(row.course.Instructor.StringVal, anchor.course.Instructor.StringVal) {
+				claim(row, anchor, MatchNormalized)
+				break
+			}
+		}
+	}
+
+	var leftRows, leftAnchors []*sourceRow
+	for _, row := range rows {
+		if row.matchedTo == nil {
+			leftRows = append(leftRows, row)
+		}
+	}
+	for _, anchor := range anchors {
+		if !claimed[anchor] {
+			leftAnchors = append(leftAnchors, anchor)
+		}
+	}
+	if len(leftRows) == 1 && len(leftAnchors) == 1 {
+		claim(leftRows[0], leftAnchors[0], MatchSection)
+	}
+}
+
+var instructorSeparatorR = regexp.MustCompile(`\s*(?:,|;|&|/|\band\b)\s*`)
+var nonNameR = regexp.MustCompile(`[^a-z-]`)
+
+// instructorNames normalizes the instructors in a Banner instructor list such
+// as "John Smith (P), Jane Doe" or "Smith, John" to a set of lowercase names
+func instructorNames(instructor string) map[string]bool {
+	names := make(map[string]bool)
+	instructor = strings.ReplaceAll(instructor, "(P)", "")
+	for _, part := range instructorSeparatorR.Split(instructor, -1) {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := nonNameR.ReplaceAllString(strings.ToLower(fields[len(fields)-1]), "")
+		if name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// ByInstructor keeps only the sections taught by an instructor, matching
+// names the same way as Reconcile
+func ByInstructor(r CourseInput, instructor string) CourseInput {
+	var isqs []scrape.CourseIsq
+	for _, isq := range r.Isqs {
+		if sameInstructor(isq.Instructor.StringVal, instructor) {
+			isqs = append(isqs, isq)
+		}
+	}
+	var grades []scrape.CourseGrades
+	for _, g := range r.Grades {
+		if sameInstructor(g.Instructor.StringVal, instructor) {
+			grades = append(grades, g)
+		}
+	}
+	var schedules []scrape.CourseSchedule
+	for _, s := range r.Schedules {
+		if sameInstructor(s.Instructor.StringVal, instructor) {
+			schedules = append(schedules, s)
+		}
+	}
+	r.Isqs, r.Grades, r.Schedules = isqs, grades, schedules
+	return r
+}
+
+// sameInstructor is whether two instructor lists have a name in common
+func sameInstructor(a, b string) bool {
+	bNames := instructorNames(b)
+	for name := range instructorNames(a) {
+		if bNames[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name      string
+		isqs      []scrape.Course
+		grades    []scrape.Course
+		schedules []scrape.Course
+		want      []Reconciliation
+		rekeyed   []string // instructors of the grades once rekeyed
+	}{
+		{
+			name:    "exact",
+			isqs:    []scrape.Course{scrapetest.Course("COP2220", 1, "Smith")},
+			grades:  []scrape.Course{scrapetest.Course("COP2220", 1, "Smith")},
+			rekeyed: []string{"Smith"},
+		},
+		{
+			name:   "normalized",
+			isqs:   []scrape.Course{scrapetest.Course("COP2220", 1, "Smith")},
+			grades: []scrape.Course{scrapetest.Course("COP2220", 1, "John Smith (P), Jane Doe")},
+			want: []Reconciliation{{
+				CsvCourse:         CsvCourse{"COP2220", scrapetest.Term, 1, "John Smith (P), Jane Doe"},
+				Source:            "grades",
+				MatchedInstructor: "Smith",
+				Match:             MatchNormalized,
+			}},
+			rekeyed: []string{"Smith"},
+		},
+		{
+			name:   "section",
+			isqs:   []scrape.Course{scrapetest.Course("COP2220", 1, "Smith")},
+			grades: []scrape.Course{scrapetest.Course("COP2220", 1, "")},
+			want: []Reconciliation{{
+				CsvCourse:         CsvCourse{"COP2220", scrapetest.Term, 1, ""},
+				Source:            "grades",
+				MatchedInstructor: "Smith",
+				Match:             MatchSection,
+			}},
+			rekeyed: []string{"Smith"},
+		},
+		{
+			name:   "team taught",
+			isqs:   []scrape.Course{scrapetest.Course("COP2220", 1, "Smith"), scrapetest.Course("COP2220", 1, "Doe")},
+			grades: []scrape.Course{scrapetest.Course("COP2220", 1, "Doe")},
+			want: []Reconciliation{{
+				CsvCourse: CsvCourse{"COP2220", scrapetest.Term, 1, "Smith"},
+				Source:    "isq",
+				Match:     MatchNone,
+			}},
+			rekeyed: []string{"Doe"},
+		},
+		{
+			name:      "schedule without isq",
+			grades:    []scrape.Course{scrapetest.Course("COP2220", 2, "Liu")},
+			schedules: []scrape.Course{scrapetest.Course("COP2220", 2, "Y. Liu")},
+			want: []Reconciliation{{
+				CsvCourse:         CsvCourse{"COP2220", scrapetest.Term, 2, "Y. Liu"},
+				Source:            "schedule",
+				MatchedInstructor: "Liu",
+				Match:             MatchNormalized,
+			}},
+			rekeyed: []string{"Liu"},
+		},
+		{
+			name:    "unmatched",
+			isqs:    []scrape.Course{scrapetest.Course("COP2220", 1, "Smith")},
+			grades:  []scrape.Course{scrapetest.Course("COP2220", 3, "Smith")},
+			rekeyed: []string{"Smith"},
+			want: []Reconciliation{
+				{CsvCourse: CsvCourse{"COP2220", scrapetest.Term, 1, "Smith"}, Source: "isq", Match: MatchNone},
+				{CsvCourse: CsvCourse{"COP2220", scrapetest.Term, 3, "Smith"}, Source: "grades", Match: MatchNone},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in CourseInput
+			for _, c := range tt.isqs {
+				in.Isqs = append(in.Isqs, scrape.CourseIsq{Course: c})
+			}
+			for _, c := range tt.grades {
+				in.Grades = append(in.Grades, scrape.CourseGrades{Course: c})
+			}
+			for _, c := range tt.schedules {
+				in.Schedules = append(in.Schedules, scrape.CourseSchedule{Course: c})
+			}
+
+			out, got := Reconcile(in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reconcile() = %+v, want %+v", got, tt.want)
+			}
+			var rekeyed []string
+			for _, g := range out.Grades {
+				rekeyed = append(rekeyed, g.Instructor.StringVal)
+			}
+			if !reflect.DeepEqual(rekeyed, tt.rekeyed) {
+				t.Errorf("rekeyed grades to %q, want %q", rekeyed, tt.rekeyed)
+			}
+
+			// The input is left as it was
+			for i, c := range tt.grades {
+				if in.Grades[i].Course != c {
+					t.Errorf("Reconcile() modified its input")
+				}
+			}
+		})
+	}
+}
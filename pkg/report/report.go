@@ -1,16 +1,17 @@
 package report
 
 import (
-	"github.com/gocarina/gocsv"
+	"encoding/json"
 	"github.com/openswoop/isqool/pkg/scrape"
+	"io"
 	"os"
 )
 
 type CsvCourse struct {
-	Name       string `csv:"course"`
-	Term       string `csv:"term"`
-	Crn        int    `csv:"crn"`
-	Instructor string `csv:"instructor"`
+	Name       string      `csv:"course" json:"course"`
+	Term       scrape.Term `csv:"term" json:"term"`
+	Crn        int         `csv:"crn" json:"crn"`
+	Instructor string      `csv:"instructor" json:"instructor"`
 }
 
 func toCsvCourse(c scrape.Course) CsvCourse {
@@ -22,13 +23,24 @@ func toCsvCourse(c scrape.Course) CsvCourse {
 }
 
 func WriteCsv(in interface{}, fileName string) error {
+	return WriteFormat(in, Csv, fileName)
+}
+
+func WriteJson(in interface{}, fileName string) error {
 	file, err := os.Create(fileName)
 	if err != nil {
 		return err
 	}
-	err = gocsv.Marshal(in, file)
-	if err != nil {
-		panic(err)
+	if err := WriteJsonTo(file, in); err != nil {
+		_ = file.Close()
+		return err
 	}
 	return file.Close()
 }
+
+// WriteJsonTo writes in as indented JSON, keeping its nesting unlike Encode
+func WriteJsonTo(w io.Writer, in interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(in)
+}
@@ -0,0 +1,488 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"cloud.google.com/go/civil"
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// weekdays are the days a meeting can be on, as Banner abbreviates them
+const weekdays = "MTWRFSU"
+
+var weekdayNames = map[byte]string{
+	'M': "Monday", 'T': "Tuesday", 'W': "Wednesday", 'R': "Thursday",
+	'F': "Friday", 'S': "Saturday", 'U': "Sunday",
+}
+
+// RoomOptions are the days and hours rooms are analyzed over. Times are in
+// minutes after midnight.
+type RoomOptions struct {
+	Days     string // the days rooms are available, like "MTWRF"
+	DayStart int    // when rooms open
+	DayEnd   int    // when rooms close
+	Slot     int    // the length of the time slots of the occupancy and grid
+	MinIdle  int    // how long a room must be free to be idle
+}
+
+// RoomBooking is a section meeting in a room every week
+type RoomBooking struct {
+	CsvCourse
+	Building  string
+	Room      int64
+	Days      string
+	Begin     int // minutes after midnight
+	End       int
+	BeginDate civil.Date
+	EndDate   civil.Date
+}
+
+// RoomSummary is how much a room is used in a week
+type RoomSummary struct {
+	Building       string  `csv:"building" json:"building"`
+	Room           int64   `csv:"room" json:"room"`
+	Sections       int     `csv:"sections" json:"sections"`
+	BookedHours    float64 `csv:"booked_hours" json:"booked_hours"`
+	Utilization    float64 `csv:"utilization" json:"utilization"` // percent of open hours booked
+	IdleHours      float64 `csv:"idle_hours" json:"idle_hours"`
+	DoubleBookings int     `csv:"double_bookings" json:"double_bookings"`
+}
+
+// DoubleBooking is two sections booked in the same room at the same time
+type DoubleBooking struct {
+	Building    string `csv:"building" json:"building"`
+	Room        int64  `csv:"room" json:"room"`
+	Days        string `csv:"days" json:"days"`
+	Begin       string `csv:"begin" json:"begin"`
+	End         string `csv:"end" json:"end"`
+	First       string `csv:"first" json:"first"`
+	Second      string `csv:"second" json:"second"`
+	CrossListed bool   `csv:"cross_listed" json:"cross_listed"` // same instructor and time, so probably intended
+}
+
+// IdleBlock is a stretch of time a room is free while it's open
+type IdleBlock struct {
+	Building string  `csv:"building" json:"building"`
+	Room     int64   `csv:"room" json:"room"`
+	Day      string  `csv:"day" json:"day"`
+	Begin    string  `csv:"begin" json:"begin"`
+	End      string  `csv:"end" json:"end"`
+	Hours    float64 `csv:"hours" json:"hours"`
+}
+
+// SlotOccupancy is how many of a building's rooms are in use in a time slot
+type SlotOccupancy struct {
+	Building  string  `csv:"building" json:"building"`
+	Day       string  `csv:"day" json:"day"`
+	Time      string  `csv:"time" json:"time"`
+	InUse     int     `csv:"in_use" json:"in_use"`
+	Rooms     int     `csv:"rooms" json:"rooms"`
+	Occupancy float64 `csv:"occupancy" json:"occupancy"` // percent of rooms in use
+}
+
+// RoomReport is the use of every room booked by some departments' schedules
+type RoomReport struct {
+	Bookings       []RoomBooking
+	Rooms          []RoomSummary
+	DoubleBookings []DoubleBooking
+	IdleBlocks     []IdleBlock
+	Occupancy      []SlotOccupancy
+	options        RoomOptions
+}
+
+// roomKey identifies a room
+type roomKey struct {
+	building string
+	room     int64
+}
+
+// interval is a span of minutes after midnight
+type interval struct {
+	begin, end int
+}
+
+// AnalyzeRooms finds the rooms the sections of department schedules meet in
+// and works out how they're used each week. Meetings without a room or time
+// are left out, as are one-off meetings like exams that span less than a
+// week.
+func AnalyzeRooms(schedules []scrape.DeptSchedule, opts RoomOptions) RoomReport {
+	// Put the days in order, once each
+	var days []byte
+	for i := range weekdays {
+		if strings.IndexByte(opts.Days, weekdays[i]) >= 0 {
+			days = append(days, weekdays[i])
+		}
+	}
+	opts.Days = string(days)
+
+	r := RoomReport{Bookings: bookRooms(schedules), options: opts}
+
+	rooms := make(map[roomKey][]RoomBooking)
+	var keys []roomKey
+	for _, b := range r.Bookings {
+		key := roomKey{b.Building, b.Room}
+		if _, found := rooms[key]; !found {
+			keys = append(keys, key)
+		}
+		rooms[key] = append(rooms[key], b)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].building != keys[j].building {
+			return keys[i].building < keys[j].building
+		}
+		return keys[i].room < keys[j].room
+	})
+
+	open := opts.DayEnd - opts.DayStart
+	for _, key := range keys {
+		bookings := rooms[key]
+		summary := RoomSummary{Building: key.building, Room: key.room}
+		sections := make(map[sectionKey]bool)
+		for _, b := range bookings {
+			sections[sectionKey{b.Name, b.Term, b.Crn}] = true
+		}
+		summary.Sections = len(sections)
+
+		booked, idle := 0, 0
+		for i := range opts.Days {
+			day := opts.Days[i]
+			busy := mergeIntervals(bookedOn(bookings, day), opts.DayStart, opts.DayEnd)
+			at := opts.DayStart
+			for _, span := range append(busy, interval{opts.DayEnd, opts.DayEnd}) {
+				if span.begin-at >= opts.MinIdle && span.begin > at {
+					idle += span.begin - at
+					r.IdleBlocks = append(r.IdleBlocks, IdleBlock{
+						Building: key.building,
+						Room:     key.room,
+						Day:      weekdayNames[day],
+						Begin:    clock(at),
+						End:      clock(span.begin),
+						Hours:    round2(float64(span.begin-at) / 60),
+					})
+				}
+				booked += span.end - span.begin
+				at = span.end
+			}
+		}
+		summary.BookedHours = round2(float64(booked) / 60)
+		summary.IdleHours = round2(float64(idle) / 60)
+		if open > 0 && len(opts.Days) > 0 {
+			summary.Utilization = round2(float64(booked) / float64(open*len(opts.Days)) * 100)
+		}
+
+		doubles := findDoubleBookings(key, bookings)
+		summary.DoubleBookings = len(doubles)
+		r.DoubleBookings = append(r.DoubleBookings, doubles...)
+		r.Rooms = append(r.Rooms, summary)
+	}
+
+	// How many of each building's rooms are in use in each slot
+	for _, building := range r.Buildings() {
+		var buildingRooms []roomKey
+		for _, key := range keys {
+			if key.building == building {
+				buildingRooms = append(buildingRooms, key)
+			}
+		}
+		for i := range opts.Days {
+			day := opts.Days[i]
+			for _, slot := range r.slots() {
+				inUse := 0
+				for _, key := range buildingRooms {
+					if len(overlapping(rooms[key], day, slot)) > 0 {
+						inUse++
+					}
+				}
+				r.Occupancy = append(r.Occupancy, SlotOccupancy{
+					Building:  building,
+					Day:       weekdayNames[day],
+					Time:      clock(slot.begin),
+					InUse:     inUse,
+					Rooms:     len(buildingRooms),
+					Occupancy: round2(float64(inUse) / float64(len(buildingRooms)) * 100),
+				})
+			}
+		}
+	}
+	return r
+}
+
+// Buildings returns the buildings with rooms booked, in order
+func (r RoomReport) Buildings() []string {
+	var buildings []string
+	for _, room := range r.Rooms {
+		if len(buildings) == 0 || buildings[len(buildings)-1] != room.Building {
+			buildings = append(buildings, room.Building)
+		}
+	}
+	return buildings
+}
+
+// Grid lays out the rooms in use in a building in every time slot of the
+// days rooms are open. Rooms booked more than once at the same time are
+// marked with the number of bookings, like "1200 (2)". The rows have a time
+// column followed by a column for each day, and can be written with
+// WriteFormat.
+func (r RoomReport) Grid(building string) interface{} {
+	rooms := make(map[int64][]RoomBooking)
+	var numbers []int64
+	for _, b := range r.Bookings {
+		if b.Building != building {
+			continue
+		}
+		if _, found := rooms[b.Room]; !found {
+			numbers = append(numbers, b.Room)
+		}
+		rooms[b.Room] = append(rooms[b.Room], b)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	days := r.options.Days
+	fields := []reflect.StructField{{Name: "Time", Type: reflect.TypeOf(""), Tag: `csv:"time" json:"time"`}}
+	for i := range days {
+		name := weekdayNames[days[i]]
+		fields = append(fields, reflect.StructField{
+			Name: name,
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(fmt.Sprintf(`csv:"%[1]s" json:"%[1]s"`, strings.ToLower(name))),
+		})
+	}
+	rowType := reflect.StructOf(fields)
+
+	grid := reflect.MakeSlice(reflect.SliceOf(rowType), 0, len(r.slots()))
+	for _, slot := range r.slots() {
+		row := reflect.New(rowType).Elem()
+		row.Field(0).SetString(clock(slot.begin) + "-" + clock(slot.end))
+		for i := range days {
+			var inUse []string
+			for _, number := range numbers {
+				switch n := concurrent(overlapping(rooms[number], days[i], slot)); {
+				case n == 1:
+					inUse = append(inUse, strconv.FormatInt(number, 10))
+				case n > 1:
+					inUse = append(inUse, fmt.Sprintf("%d (%d)", number, n))
+				}
+			}
+			row.Field(i + 1).SetString(strings.Join(inUse, ", "))
+		}
+		grid = reflect.Append(grid, row)
+	}
+	return grid.Interface()
+}
+
+// slots splits the hours rooms are open into time slots
+func (r RoomReport) slots() []interval {
+	var slots []interval
+	if r.options.Slot <= 0 {
+		return nil
+	}
+	for at := r.options.DayStart; at < r.options.DayEnd; at += r.options.Slot {
+		end := at + r.options.Slot
+		if end > r.options.DayEnd {
+			end = r.options.DayEnd
+		}
+		slots = append(slots, interval{at, end})
+	}
+	return slots
+}
+
+// WriteRoomTable prints how much each room is used as an aligned table
+func WriteRoomTable(w io.Writer, rooms []RoomSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BUILDING\tROOM\tSECTIONS\tBOOKED HOURS\tUTILIZATION %\tIDLE HOURS\tDOUBLE BOOKINGS")
+	for _, r := range rooms {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%d\n",
+			r.Building, r.Room, r.Sections, r.BookedHours, r.Utilization, r.IdleHours, r.DoubleBookings)
+	}
+	return tw.Flush()
+}
+
+// WriteDoubleBookingTable prints the double-booked rooms as an aligned table
+func WriteDoubleBookingTable(w io.Writer, doubles []DoubleBooking) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BUILDING\tROOM\tDAYS\tTIME\tFIRST\tSECOND\tNOTE")
+	for _, d := range doubles {
+		note := ""
+		if d.CrossListed {
+			note = "likely cross-listed"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s-%s\t%s\t%s\t%s\n",
+			d.Building, d.Room, d.Days, d.Begin, d.End, d.First, d.Second, note)
+	}
+	return tw.Flush()
+}
+
+// WriteIdleTable prints the idle blocks of rooms as an aligned table
+func WriteIdleTable(w io.Writer, blocks []IdleBlock) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BUILDING\tROOM\tDAY\tTIME\tHOURS")
+	for _, b := range blocks {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s-%s\t%.2f\n", b.Building, b.Room, b.Day, b.Begin, b.End, b.Hours)
+	}
+	return tw.Flush()
+}
+
+// bookRooms lists the weekly meetings of sections that are in a room
+func bookRooms(schedules []scrape.DeptSchedule) []RoomBooking {
+	var bookings []RoomBooking
+	for _, section := range schedules {
+		for _, m := range section.Meetings {
+			if !m.Building.Valid || !m.Room.Valid || !m.Days.Valid || !m.BeginTime.Valid || !m.EndTime.Valid {
+				continue
+			}
+			if m.BeginDate.IsValid() && m.EndDate.IsValid() && m.EndDate.DaysSince(m.BeginDate) < 6 {
+				continue
+			}
+			bookings = append(bookings, RoomBooking{
+				CsvCourse: toCsvCourse(section.Course),
+				Building:  m.Building.StringVal,
+				Room:      m.Room.Int64,
+				Days:      m.Days.StringVal,
+				Begin:     m.BeginTime.Time.Hour*60 + m.BeginTime.Time.Minute,
+				End:       m.EndTime.Time.Hour*60 + m.EndTime.Time.Minute,
+				BeginDate: m.BeginDate,
+				EndDate:   m.EndDate,
+			})
+		}
+	}
+	return bookings
+}
+
+// findDoubleBookings finds the pairs of sections booked in a room at the
+// same time on the same days
+func findDoubleBookings(key roomKey, bookings []RoomBooking) []DoubleBooking {
+	var doubles []DoubleBooking
+	for i, a := range bookings {
+		for _, b := range bookings[i+1:] {
+			if a.Name == b.Name && a.Term == b.Term && a.Crn == b.Crn {
+				continue
+			}
+			begin, end := maxInt(a.Begin, b.Begin), minInt(a.End, b.End)
+			if begin >= end || !datesOverlap(a, b) {
+				continue
+			}
+			var days []byte
+			for j := range weekdays {
+				if strings.IndexByte(a.Days, weekdays[j]) >= 0 && strings.IndexByte(b.Days, weekdays[j]) >= 0 {
+					days = append(days, weekdays[j])
+				}
+			}
+			if len(days) == 0 {
+				continue
+			}
+			doubles = append(doubles, DoubleBooking{
+				Building:    key.building,
+				Room:        key.room,
+				Days:        string(days),
+				Begin:       clock(begin),
+				End:         clock(end),
+				First:       describeBooking(a),
+				Second:      describeBooking(b),
+				CrossListed: a.Instructor != "" && a.Instructor == b.Instructor && a.Begin == b.Begin && a.End == b.End,
+			})
+		}
+	}
+	return doubles
+}
+
+// describeBooking names the section of a booking, like "COP2220 80001 (Liu)"
+func describeBooking(b RoomBooking) string {
+	s := fmt.Sprintf("%s %d", b.Name, b.Crn)
+	if b.Instructor != "" {
+		s += " (" + b.Instructor + ")"
+	}
+	return s
+}
+
+// datesOverlap reports whether two bookings share a date, assuming they do
+// if either's dates aren't known
+func datesOverlap(a, b RoomBooking) bool {
+	if !a.BeginDate.IsValid() || !a.EndDate.IsValid() || !b.BeginDate.IsValid() || !b.EndDate.IsValid() {
+		return true
+	}
+	return a.BeginDate.DaysSince(b.EndDate) <= 0 && b.BeginDate.DaysSince(a.EndDate) <= 0
+}
+
+// bookedOn returns the times a room is booked on a day
+func bookedOn(bookings []RoomBooking, day byte) []interval {
+	var spans []interval
+	for _, b := range bookings {
+		if strings.IndexByte(b.Days, day) >= 0 {
+			spans = append(spans, interval{b.Begin, b.End})
+		}
+	}
+	return spans
+}
+
+// overlapping returns the bookings of a room on a day that overlap a slot
+func overlapping(bookings []RoomBooking, day byte, slot interval) []RoomBooking {
+	var found []RoomBooking
+	for _, b := range bookings {
+		if strings.IndexByte(b.Days, day) >= 0 && b.Begin < slot.end && slot.begin < b.End {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// concurrent is the most bookings that are held on the same date, out of
+// bookings at the same time. Sections in different parts of term can share
+// a room at the same time without being double-booked.
+func concurrent(bookings []RoomBooking) int {
+	most := 0
+	for _, b := range bookings {
+		n := 0
+		for _, other := range bookings {
+			if datesOverlap(other, RoomBooking{BeginDate: b.BeginDate, EndDate: b.BeginDate}) {
+				n++
+			}
+		}
+		if n > most {
+			most = n
+		}
+	}
+	return most
+}
+
+// mergeIntervals merges overlapping spans and clips them to [from, to)
+func mergeIntervals(spans []interval, from, to int) []interval {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].begin < spans[j].begin })
+	var merged []interval
+	for _, span := range spans {
+		span.begin, span.end = maxInt(span.begin, from), minInt(span.end, to)
+		if span.begin >= span.end {
+			continue
+		}
+		if n := len(merged); n > 0 && span.begin <= merged[n-1].end {
+			merged[n-1].end = maxInt(merged[n-1].end, span.end)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// clock formats minutes after midnight like "13:30"
+func clock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
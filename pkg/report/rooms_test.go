@@ -0,0 +1,132 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+// roomSchedules are sections meeting in two rooms of building 15 in the
+// mornings
+func roomSchedules() []scrape.DeptSchedule {
+	course, section, meeting := scrapetest.Course, scrapetest.Section, scrapetest.Meeting
+	fallStart, fallEnd := scrapetest.TermStart, scrapetest.TermEnd
+	finalExam := civil.Date{Year: 2023, Month: 12, Day: 5}
+	return []scrape.DeptSchedule{
+		// Two sections overlapping on Mondays and Wednesdays, one with a
+		// final exam that isn't a weekly booking
+		section(course("COP2220", 1, "Liu"), meeting("MW", 1000, 1050, 1200, fallStart, fallEnd),
+			meeting("T", 800, 1000, 1200, finalExam, finalExam)),
+		section(course("COP3530", 2, "Smith"), meeting("MW", 1030, 1145, 1200, fallStart, fallEnd)),
+
+		// Sections in the two halves of the term sharing a time
+		section(course("COP1000", 3, "Roy"),
+			meeting("TR", 800, 900, 1200, fallStart, civil.Date{Year: 2023, Month: 10, Day: 10})),
+		section(course("COP1001", 4, "Roy"),
+			meeting("TR", 800, 900, 1200, civil.Date{Year: 2023, Month: 10, Day: 16}, fallEnd)),
+
+		// A cross-listed pair taught together
+		section(course("COP4710", 5, "Doe"), meeting("TR", 930, 1045, 1300, fallStart, fallEnd)),
+		section(course("COP5711", 6, "Doe"), meeting("TR", 930, 1045, 1300, fallStart, fallEnd)),
+
+		// Online sections aren't in a room
+		section(course("COP9999", 7, "Web")),
+	}
+}
+
+func TestAnalyzeRooms(t *testing.T) {
+	opts := RoomOptions{Days: "MTWRF", DayStart: 8 * 60, DayEnd: 12 * 60, Slot: 60, MinIdle: 60}
+	r := AnalyzeRooms(roomSchedules(), opts)
+
+	wantRooms := []RoomSummary{
+		{Building: "15", Room: 1200, Sections: 4, BookedHours: 5.5, Utilization: 27.5, IdleHours: 14, DoubleBookings: 1},
+		{Building: "15", Room: 1300, Sections: 2, BookedHours: 2.5, Utilization: 12.5, IdleHours: 17.5, DoubleBookings: 1},
+	}
+	if !reflect.DeepEqual(r.Rooms, wantRooms) {
+		t.Errorf("Rooms = %+v, want %+v", r.Rooms, wantRooms)
+	}
+
+	wantDoubles := []DoubleBooking{
+		{Building: "15", Room: 1200, Days: "MW", Begin: "10:30", End: "10:50",
+			First: "COP2220 1 (Liu)", Second: "COP3530 2 (Smith)"},
+		{Building: "15", Room: 1300, Days: "TR", Begin: "09:30", End: "10:45",
+			First: "COP4710 5 (Doe)", Second: "COP5711 6 (Doe)", CrossListed: true},
+	}
+	if !reflect.DeepEqual(r.DoubleBookings, wantDoubles) {
+		t.Errorf("DoubleBookings = %+v, want %+v", r.DoubleBookings, wantDoubles)
+	}
+
+	wantIdle := []IdleBlock{
+		{Building: "15", Room: 1200, Day: "Monday", Begin: "08:00", End: "10:00", Hours: 2},
+		{Building: "15", Room: 1200, Day: "Tuesday", Begin: "09:00", End: "12:00", Hours: 3},
+		{Building: "15", Room: 1200, Day: "Wednesday", Begin: "08:00", End: "10:00", Hours: 2},
+		{Building: "15", Room: 1200, Day: "Thursday", Begin: "09:00", End: "12:00", Hours: 3},
+		{Building: "15", Room: 1200, Day: "Friday", Begin: "08:00", End: "12:00", Hours: 4},
+	}
+	var idle []IdleBlock
+	for _, block := range r.IdleBlocks {
+		if block.Room == 1200 {
+			idle = append(idle, block)
+		}
+	}
+	if !reflect.DeepEqual(idle, wantIdle) {
+		t.Errorf("IdleBlocks of 1200 = %+v, want %+v", idle, wantIdle)
+	}
+
+	if got := r.Buildings(); !reflect.DeepEqual(got, []string{"15"}) {
+		t.Errorf("Buildings() = %q, want [15]", got)
+	}
+	wantOccupancy := SlotOccupancy{Building: "15", Day: "Tuesday", Time: "09:00", InUse: 1, Rooms: 2, Occupancy: 50}
+	if got := r.Occupancy[5]; got != wantOccupancy {
+		t.Errorf("Occupancy[5] = %+v, want %+v", got, wantOccupancy)
+	}
+}
+
+func TestRoomGrid(t *testing.T) {
+	tests := []struct {
+		days string
+		want [][]string
+	}{
+		{
+			// Days are put in order, and the halves of the term don't
+			// double-book their room
+			days: "TM",
+			want: [][]string{
+				{"08:00-09:00", "", "1200"},
+				{"09:00-10:00", "", "1300 (2)"},
+				{"10:00-11:00", "1200 (2)", "1300 (2)"},
+				{"11:00-12:00", "1200", ""},
+			},
+		},
+		{
+			days: "F",
+			want: [][]string{
+				{"08:00-09:00", ""},
+				{"09:00-10:00", ""},
+				{"10:00-11:00", ""},
+				{"11:00-12:00", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.days, func(t *testing.T) {
+			opts := RoomOptions{Days: tt.days, DayStart: 8 * 60, DayEnd: 12 * 60, Slot: 60, MinIdle: 60}
+			grid := reflect.ValueOf(AnalyzeRooms(roomSchedules(), opts).Grid("15"))
+			var got [][]string
+			for i := 0; i < grid.Len(); i++ {
+				row := grid.Index(i)
+				var cells []string
+				for j := 0; j < row.NumField(); j++ {
+					cells = append(cells, row.Field(j).String())
+				}
+				got = append(got, cells)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Grid(15) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// SeatPoint is how full a section was when a snapshot of it was taken
+type SeatPoint struct {
+	CsvCourse
+	TakenAt   time.Time `csv:"taken_at" json:"taken_at"`
+	Capacity  int       `csv:"capacity" json:"capacity"`
+	Actual    int       `csv:"actual" json:"actual"`
+	Remaining int       `csv:"remaining" json:"remaining"`
+	Waitlist  int       `csv:"waitlist" json:"waitlist"`
+	FillRate  float64   `csv:"fill_rate" json:"fill_rate"` // percent of capacity taken
+}
+
+// SeatCurve is how a section filled up over its snapshots, oldest first
+type SeatCurve struct {
+	CsvCourse
+	Points []SeatPoint
+}
+
+// sparks are the bars of a fill curve, from empty to full
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// BuildSeatCurves groups snapshots into the fill curve of each section,
+// ordered by course and CRN
+func BuildSeatCurves(snapshots []scrape.SectionSnapshot) []SeatCurve {
+	curves := make(map[sectionKey]*SeatCurve)
+	var keys []sectionKey
+	for _, s := range snapshots {
+		key := toSectionKey(s.Course)
+		curve, found := curves[key]
+		if !found {
+			curve = &SeatCurve{CsvCourse: toCsvCourse(s.Course)}
+			curves[key] = curve
+			keys = append(keys, key)
+		}
+		point := SeatPoint{
+			CsvCourse: curve.CsvCourse,
+			TakenAt:   s.TakenAt,
+			Capacity:  s.Capacity,
+			Actual:    s.Actual,
+			Remaining: s.Remaining,
+			Waitlist:  s.WaitlistActual,
+		}
+		if s.Capacity > 0 {
+			point.FillRate = round2(float64(s.Actual) / float64(s.Capacity) * 100)
+		}
+		curve.Points = append(curve.Points, point)
+	}
+
+	result := make([]SeatCurve, 0, len(keys))
+	for _, key := range keys {
+		curve := curves[key]
+		sort.SliceStable(curve.Points, func(i, j int) bool {
+			return curve.Points[i].TakenAt.Before(curve.Points[j].TakenAt)
+		})
+		result = append(result, *curve)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Crn < result[j].Crn
+	})
+	return result
+}
+
+// SeatPoints flattens fill curves into their points, for writing to a file
+func SeatPoints(curves []SeatCurve) []SeatPoint {
+	var points []SeatPoint
+	for _, curve := range curves {
+		points = append(points, curve.Points...)
+	}
+	return points
+}
+
+// WriteSeatTable prints each section's latest seats and its fill curve as
+// an aligned table
+func WriteSeatTable(w io.Writer, curves []SeatCurve) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COURSE\tCRN\tINSTRUCTOR\tCAPACITY\tENROLLED\tWAITLIST\tFILL %\tSNAPSHOTS\tSINCE\tCURVE")
+	for _, curve := range curves {
+		if len(curve.Points) == 0 {
+			continue
+		}
+		first, last := curve.Points[0], curve.Points[len(curve.Points)-1]
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%d\t%d\t%.2f\t%d\t%s\t%s\n",
+			curve.Name, curve.Crn, curve.Instructor, last.Capacity, last.Actual, last.Waitlist,
+			last.FillRate, len(curve.Points), first.TakenAt.Local().Format("2006-01-02"), sparkline(curve.Points))
+	}
+	return tw.Flush()
+}
+
+// sparkline draws the fill rate of each point as a bar
+func sparkline(points []SeatPoint) string {
+	line := make([]rune, len(points))
+	for i, p := range points {
+		level := int(p.FillRate / 100 * float64(len(sparks)-1))
+		if level < 0 {
+			level = 0
+		} else if level >= len(sparks) {
+			level = len(sparks) - 1
+		}
+		line[i] = sparks[level]
+	}
+	return string(line)
+}
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+type DepartmentInput struct {
+	Schedules []scrape.DeptSchedule
+	Isqs      []scrape.CourseIsq
+	Grades    []scrape.CourseGrades
+}
+
+// DepartmentSummary totals the sections of a course or of an instructor in
+// one term. Course rows leave Instructor blank and instructor rows leave
+// Course blank.
+type DepartmentSummary struct {
+	Term       scrape.Term `csv:"term" json:"term"`
+	Course     string      `csv:"course" json:"course"`
+	Instructor string      `csv:"instructor" json:"instructor"`
+	Sections   int         `csv:"sections" json:"sections"`
+	Enrolled   int         `csv:"enrolled" json:"enrolled"`
+	WaitCount  int         `csv:"wait_count" json:"wait_count"`
+	Rating     float64     `csv:"rating" json:"rating"`
+	AverageGpa float64     `csv:"average_gpa" json:"average_gpa"`
+	InPerson   int         `csv:"in_person" json:"in_person"`
+	Hybrid     int         `csv:"hybrid" json:"hybrid"`
+	Online     int         `csv:"online" json:"online"`
+}
+
+// sectionKey identifies a section regardless of how its instructor is written
+type sectionKey struct {
+	Name string
+	Term scrape.Term
+	Crn  int
+}
+
+func toSectionKey(c scrape.Course) sectionKey {
+	return sectionKey{c.Name, c.Term, c.Crn}
+}
+
+// summaryTotals accumulates the sections of one summary row
+type summaryTotals struct {
+	DepartmentSummary
+	ratingSum    float64
+	ratingWeight float64
+	gpaSum       float64
+	gpaWeight    float64
+}
+
+// SummarizeDepartment joins a department's schedules with the ISQs and grades
+// of its sections and totals them by course and by instructor for each term
+func SummarizeDepartment(d DepartmentInput) []DepartmentSummary {
+	isqs := make(map[sectionKey]scrape.Isq, len(d.Isqs))
+	for _, v := range d.Isqs {
+		isqs[toSectionKey(v.Course)] = v.Isq
+	}
+	grades := make(map[sectionKey]scrape.Grades, len(d.Grades))
+	for _, v := range d.Grades {
+		grades[toSectionKey(v.Course)] = v.Grades
+	}
+
+	type groupKey struct {
+		term               scrape.Term
+		course, instructor string
+	}
+	totals := make(map[groupKey]*summaryTotals)
+	var keys []groupKey
+	for _, section := range d.Schedules {
+		if !section.Term.Valid() {
+			continue
+		}
+		isq, hasIsq := isqs[toSectionKey(section.Course)]
+		grade, hasGrades := grades[toSectionKey(section.Course)]
+		groups := []groupKey{
+			{term: section.Term, course: section.Name},
+			{term: section.Term, instructor: parseNullString(section.Instructor)},
+		}
+		for _, key := range groups {
+			t, found := totals[key]
+			if !found {
+				t = &summaryTotals{}
+				t.Term, t.Course, t.Instructor = key.term, key.course, key.instructor
+				totals[key] = t
+				keys = append(keys, key)
+			}
+			t.add(section, isq, hasIsq, grade, hasGrades)
+		}
+	}
+
+	// Order by term, then courses before instructors
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := totals[keys[i]], totals[keys[j]]
+		if c := a.Term.Compare(b.Term); c != 0 {
+			return c < 0
+		}
+		if (a.Course == "") != (b.Course == "") {
+			return a.Course != ""
+		}
+		return a.Course+a.Instructor < b.Course+b.Instructor
+	})
+
+	summaries := make([]DepartmentSummary, len(keys))
+	for i, key := range keys {
+		t := totals[key]
+		if t.ratingWeight > 0 {
+			t.Rating = round2(t.ratingSum / t.ratingWeight)
+		}
+		if t.gpaWeight > 0 {
+			t.AverageGpa = round2(t.gpaSum / t.gpaWeight)
+		}
+		summaries[i] = t.DepartmentSummary
+	}
+	return summaries
+}
+
+func (t *summaryTotals) add(section scrape.DeptSchedule, isq scrape.Isq, hasIsq bool, grade scrape.Grades, hasGrades bool) {
+	t.Sections++
+	t.WaitCount += section.WaitCount
+	switch modality(section) {
+	case "in_person":
+		t.InPerson++
+	case "hybrid":
+		t.Hybrid++
+	default:
+		t.Online++
+	}
+
+	if !hasIsq {
+		return
+	}
+	weight := float64(isq.Enrolled)
+	t.Enrolled += isq.Enrolled
+	if isq.Responded > 0 {
+		t.ratingSum += isq.Rating * weight
+		t.ratingWeight += weight
+	}
+	if hasGrades {
+		t.gpaSum += grade.Average * weight
+		t.gpaWeight += weight
+	}
+}
+
+// modality classifies a section as "in_person" if all of its meetings are in
+// a room, "hybrid" if only some are, and "online" otherwise
+func modality(section scrape.DeptSchedule) string {
+	var physical, remote int
+	for _, meeting := range section.Meetings {
+		building := strings.ToUpper(parseNullString(meeting.Building))
+		if building == "" || strings.Contains(building, "ONLINE") || strings.Contains(building, "TBA") {
+			remote++
+		} else {
+			physical++
+		}
+	}
+	switch {
+	case physical > 0 && remote == 0:
+		return "in_person"
+	case physical > 0:
+		return "hybrid"
+	default:
+		return "online"
+	}
+}
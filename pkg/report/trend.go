@@ -0,0 +1,249 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// Minimum number of terms needed before a slope is considered meaningful
+const minTrendTerms = 4
+
+// t95 are the critical values of a two-sided 95% t-test by degrees of
+// freedom, starting at 1. Beyond the table the normal value is close enough.
+var t95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical is the critical value of a two-sided 95% t-test
+func tCritical(df int) float64 {
+	if df > len(t95) {
+		return z95
+	}
+	return t95[df-1]
+}
+
+// TermPoint is one term of a time series, along with trailing rolling
+// averages over the previous terms
+type TermPoint struct {
+	Term                scrape.Term `csv:"term" json:"term"`
+	Sections            int         `csv:"sections" json:"sections"`
+	Enrolled            int         `csv:"enrolled" json:"enrolled"`
+	Rating              float64     `csv:"rating" json:"rating"`
+	AverageGpa          float64     `csv:"average_gpa" json:"average_gpa"`
+	ResponseRate        float64     `csv:"response_rate" json:"response_rate"`
+	RollingEnrolled     float64     `csv:"rolling_enrolled" json:"rolling_enrolled"`
+	RollingRating       float64     `csv:"rolling_rating" json:"rolling_rating"`
+	RollingAverageGpa   float64     `csv:"rolling_average_gpa" json:"rolling_average_gpa"`
+	RollingResponseRate float64     `csv:"rolling_response_rate" json:"rolling_response_rate"`
+}
+
+// TrendSlope is the least squares slope of a metric per term. Drifting is
+// set when the slope is significantly different from zero.
+type TrendSlope struct {
+	Metric   string  `csv:"metric" json:"metric"`
+	Slope    float64 `csv:"slope" json:"slope"`
+	TStat    float64 `csv:"t_stat" json:"t_stat"`
+	Drifting bool    `csv:"drifting" json:"drifting"`
+}
+
+type TrendReport struct {
+	Points []TermPoint  `json:"points"`
+	Slopes []TrendSlope `json:"slopes"`
+}
+
+// termTotals accumulates the sections offered in one term
+type termTotals struct {
+	sections     int
+	enrolled     int
+	responded    int
+	ratingSum    float64
+	ratingWeight float64
+	gpaSum       float64
+	gpaWeight    float64
+}
+
+// Trend aggregates the sections of a course or instructor into a per-term
+// time series. Ratings and GPAs are weighted by enrollment and the rolling
+// averages trail over the given number of terms, counting terms the course
+// wasn't offered in.
+func Trend(r CourseInput, window int) TrendReport {
+	if window < 1 {
+		window = 1
+	}
+
+	totals := make(map[scrape.Term]*termTotals)
+	var terms []scrape.Term
+	for _, row := range joinCourse(r) {
+		t, found := totals[row.CsvCourse.Term]
+		if !found {
+			if !row.CsvCourse.Term.Valid() {
+				continue
+			}
+			t = &termTotals{}
+			totals[row.CsvCourse.Term] = t
+			terms = append(terms, row.CsvCourse.Term)
+		}
+
+		t.sections++
+		if row.Isq == nil {
+			continue // enrollment is only known from the ISQ
+		}
+		weight := float64(row.Enrolled)
+		t.enrolled += row.Enrolled
+		t.responded += row.Responded
+		if row.Responded > 0 {
+			t.ratingSum += row.Rating * weight
+			t.ratingWeight += weight
+		}
+		if row.Grades != nil {
+			t.gpaSum += row.Average * weight
+			t.gpaWeight += weight
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].Before(terms[j])
+	})
+
+	var enrolled, ratings, gpas, responseRates series
+	points := make([]TermPoint, len(terms))
+	for i, term := range terms {
+		t := totals[term]
+		p := TermPoint{
+			Term:     term,
+			Sections: t.sections,
+			Enrolled: t.enrolled,
+		}
+		x, _ := term.Ordinal() // valid, as checked above
+		enrolled.add(x, float64(t.enrolled), t.enrolled > 0)
+		if t.ratingWeight > 0 {
+			p.Rating = round2(t.ratingSum / t.ratingWeight)
+		}
+		ratings.add(x, p.Rating, t.ratingWeight > 0)
+		if t.gpaWeight > 0 {
+			p.AverageGpa = round2(t.gpaSum / t.gpaWeight)
+		}
+		gpas.add(x, p.AverageGpa, t.gpaWeight > 0)
+		if t.enrolled > 0 {
+			p.ResponseRate = round2(float64(t.responded) / float64(t.enrolled) * 100)
+		}
+		responseRates.add(x, p.ResponseRate, t.enrolled > 0)
+
+		p.RollingEnrolled = enrolled.rolling(x, window)
+		p.RollingRating = ratings.rolling(x, window)
+		p.RollingAverageGpa = gpas.rolling(x, window)
+		p.RollingResponseRate = responseRates.rolling(x, window)
+		points[i] = p
+	}
+
+	return TrendReport{
+		Points: points,
+		Slopes: []TrendSlope{
+			enrolled.slope("enrolled"),
+			ratings.slope("rating"),
+			gpas.slope("average_gpa"),
+			responseRates.slope("response_rate"),
+		},
+	}
+}
+
+// series is a metric observed in some terms, placed by their ordinals so
+// that terms without sections leave a gap
+type series struct {
+	x, y []float64
+}
+
+func (s *series) add(ordinal int, value float64, valid bool) {
+	if valid {
+		s.x = append(s.x, float64(ordinal))
+		s.y = append(s.y, value)
+	}
+}
+
+// rolling averages the values observed in the window of terms ending at the
+// term with an ordinal
+func (s series) rolling(ordinal int, window int) float64 {
+	var sum float64
+	var n int
+	for j := range s.x {
+		if s.x[j] <= float64(ordinal) && s.x[j] > float64(ordinal-window) {
+			sum += s.y[j]
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return round2(sum / float64(n))
+}
+
+// slope fits a least squares line through the series and tests whether its
+// slope differs from zero at the 95% level, with a t-test on n-2 degrees of
+// freedom
+func (s series) slope(metric string) TrendSlope {
+	trend := TrendSlope{Metric: metric}
+	n := float64(len(s.x))
+	if len(s.x) < minTrendTerms {
+		return trend
+	}
+
+	var meanX, meanY float64
+	for i := range s.x {
+		meanX += s.x[i] / n
+		meanY += s.y[i] / n
+	}
+	var sxx, sxy, syy float64
+	for i := range s.x {
+		sxx += (s.x[i] - meanX) * (s.x[i] - meanX)
+		sxy += (s.x[i] - meanX) * (s.y[i] - meanY)
+		syy += (s.y[i] - meanY) * (s.y[i] - meanY)
+	}
+	slope := sxy / sxx
+	var sse float64
+	for i := range s.x {
+		residual := s.y[i] - meanY - slope*(s.x[i]-meanX)
+		sse += residual * residual
+	}
+	flat := true
+	for _, y := range s.y {
+		flat = flat && y == s.y[0]
+	}
+	if flat {
+		return trend
+	}
+	trend.Slope = slope
+
+	// A line through every point leaves no error to test against, so it
+	// drifts by its slope alone. Rounding leaves a little error behind.
+	if sse <= 1e-12*syy {
+		trend.Drifting = true
+		return trend
+	}
+	tStat := slope / math.Sqrt(sse/(n-2)/sxx)
+	trend.TStat = tStat
+	trend.Drifting = math.Abs(tStat) >= tCritical(len(s.x)-2)
+	return trend
+}
+
+// WriteTrendTable prints the time series and its slopes as aligned tables
+func WriteTrendTable(w io.Writer, t TrendReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TERM\tSECTIONS\tENROLLED\tRATING\tGPA\tRESPONSE %\tAVG ENROLLED\tAVG RATING\tAVG GPA\tAVG RESPONSE %")
+	for _, p := range t.Points {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n",
+			p.Term, p.Sections, p.Enrolled, p.Rating, p.AverageGpa, p.ResponseRate,
+			p.RollingEnrolled, p.RollingRating, p.RollingAverageGpa, p.RollingResponseRate)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "METRIC\tSLOPE/TERM\tT\tDRIFTING")
+	for _, s := range t.Slopes {
+		fmt.Fprintf(tw, "%s\t%.3f\t%.2f\t%t\n", s.Metric, s.Slope, s.TStat, s.Drifting)
+	}
+	return tw.Flush()
+}
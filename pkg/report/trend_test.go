@@ -0,0 +1,98 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+// termIsq makes the ISQ of a section of COP2220 in a term, rated 4 by every
+// response
+func termIsq(term scrape.Term, instructor string, enrolled int) scrape.CourseIsq {
+	c := scrapetest.Course("COP2220", 10001, instructor)
+	c.Term = term
+	return scrapetest.Isq(c, enrolled, enrolled/2, [5]float64{0, 100, 0, 0, 0})
+}
+
+func TestTrend(t *testing.T) {
+	tests := []struct {
+		name        string
+		isqs        []scrape.CourseIsq
+		instructor  string
+		wantRolling []float64 // rolling enrollment by term
+		wantSlope   TrendSlope
+	}{
+		{
+			name: "consecutive terms",
+			isqs: []scrape.CourseIsq{
+				termIsq("Spring 2023", "Smith", 10),
+				termIsq("Summer 2023", "Smith", 20),
+				termIsq("Fall 2023", "Smith", 30),
+				termIsq("Spring 2024", "Smith", 40),
+			},
+			wantRolling: []float64{10, 15, 25, 35},
+			wantSlope:   TrendSlope{Metric: "enrolled", Slope: 10, Drifting: true},
+		},
+		{
+			name: "skipped terms",
+			isqs: []scrape.CourseIsq{
+				termIsq("Fall 2020", "Smith", 10),
+				termIsq("Fall 2021", "Smith", 20),
+				termIsq("Fall 2022", "Smith", 30),
+				termIsq("Fall 2023", "Smith", 40),
+			},
+			wantRolling: []float64{10, 20, 30, 40},
+			wantSlope:   TrendSlope{Metric: "enrolled", Slope: 10.0 / 3, Drifting: true},
+		},
+		{
+			name: "term without enrollment",
+			isqs: []scrape.CourseIsq{
+				termIsq("Spring 2023", "Smith", 10),
+				termIsq("Summer 2023", "Smith", 20),
+				termIsq("Fall 2023", "Smith", 0),
+				termIsq("Spring 2024", "Smith", 40),
+				termIsq("Summer 2024", "Smith", 50),
+			},
+			wantRolling: []float64{10, 15, 20, 40, 45},
+			wantSlope:   TrendSlope{Metric: "enrolled", Slope: 10, Drifting: true},
+		},
+		{
+			name: "instructor",
+			isqs: []scrape.CourseIsq{
+				termIsq("Spring 2023", "Smith", 10),
+				termIsq("Summer 2023", "Smith (P), Jones", 20),
+				termIsq("Fall 2023", "John Smith", 30),
+				termIsq("Spring 2024", "Jones", 5),
+				termIsq("Summer 2024", "SMITH", 40),
+			},
+			instructor:  "Smith",
+			wantRolling: []float64{10, 15, 25, 40},
+			wantSlope:   TrendSlope{Metric: "enrolled", Slope: 7.43, TStat: 7.51, Drifting: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := CourseInput{Isqs: tt.isqs}
+			if tt.instructor != "" {
+				in = ByInstructor(in, tt.instructor)
+			}
+			trend := Trend(in, 2)
+
+			var rolling []float64
+			for _, p := range trend.Points {
+				rolling = append(rolling, p.RollingEnrolled)
+			}
+			if !reflect.DeepEqual(rolling, tt.wantRolling) {
+				t.Errorf("rolling enrolled = %v, want %v", rolling, tt.wantRolling)
+			}
+			slope := trend.Slopes[0]
+			slope.Slope, slope.TStat = round2(slope.Slope), round2(slope.TStat)
+			tt.wantSlope.Slope = round2(tt.wantSlope.Slope)
+			if slope != tt.wantSlope {
+				t.Errorf("enrolled slope = %+v, want %+v", slope, tt.wantSlope)
+			}
+		})
+	}
+}
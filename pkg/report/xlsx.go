@@ -0,0 +1,106 @@
+package report
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The smallest set of parts Excel needs to open a workbook with one sheet
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="isqool" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+// encodeXlsx writes the records as a single sheet workbook with a header row
+func encodeXlsx(w io.Writer, columns []column, records [][]interface{}) error {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]interface{}, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	writeXlsxRow(&sb, 1, header)
+	for i, record := range records {
+		writeXlsxRow(&sb, i+2, record)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	if _, err := io.WriteString(f, sb.String()); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func writeXlsxRow(sb *strings.Builder, row int, values []interface{}) {
+	fmt.Fprintf(sb, `<row r="%d">`, row)
+	for i, value := range values {
+		ref := xlsxColumn(i) + strconv.Itoa(row)
+		switch v := value.(type) {
+		case nil:
+			continue
+		case int64:
+			fmt.Fprintf(sb, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			fmt.Fprintf(sb, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(sb, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		default:
+			fmt.Fprintf(sb, `<c r="%s" t="inlineStr"><is><t>`, ref)
+			_ = xml.EscapeText(sb, []byte(fmt.Sprint(v)))
+			sb.WriteString(`</t></is></c>`)
+		}
+	}
+	sb.WriteString(`</row>`)
+}
+
+// xlsxColumn converts a zero-based column index to its letters (A, B, ... AA)
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,179 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// ListCourses finds the names of every course in a subject (e.g. COP) listed
+// in the catalog for a term, such as "COP2220"
+func ListCourses(c *colly.Collector, subject string, term Term) ([]string, error) {
+	if err := Current.require(CatalogPage); err != nil {
+		return nil, err
+	}
+	termId, err := term.Id()
+	if err != nil {
+		return nil, err
+	}
+	subject = strings.ToUpper(strings.TrimSpace(subject))
+
+	var courses []string
+	seen := make(map[string]bool)
+
+	// Each course is listed under a title like "COP 2220 - Computer Science I"
+	c.OnHTML("td.nttitle a", func(e *colly.HTMLElement) {
+		fields := strings.Fields(strings.Split(e.Text, " - ")[0])
+		if len(fields) != 2 || fields[0] != subject {
+			return
+		}
+		name := fields[0] + fields[1]
+		if !seen[name] {
+			courses = append(courses, name)
+			seen[name] = true
+		}
+	})
+
+	return courses, c.Visit(fmt.Sprintf(
+		"%vbwckctlg.p_display_courses?term_in=%d&one_subj=%v&sel_crse_strt=&sel_crse_end=&sel_subj=&sel_levl=&sel_schd=&sel_coll=&sel_divs=&sel_dept=&sel_attr=",
+		Current.BannerUrl, termId, url.QueryEscape(subject)))
+}
+
+// CatalogEntry is a course's description in the catalog, along with the
+// requirements for taking it
+type CatalogEntry struct {
+	Name          string `db:"name" csv:"course" bigquery:"course"`
+	Term          Term   `db:"term" csv:"term" bigquery:"term"` // the catalog it was read from
+	Title         string `db:"title" csv:"title" bigquery:"title"`
+	Description   string `db:"description" csv:"description" bigquery:"description"`
+	Credits       string `db:"credits" csv:"credits" bigquery:"credits"` // e.g. "3.000" or "1.000 TO 4.000"
+	Levels        List   `db:"levels" csv:"levels" bigquery:"levels"`
+	ScheduleTypes List   `db:"schedule_types" csv:"schedule_types" bigquery:"schedule_types"`
+	Department    string `db:"department" csv:"department" bigquery:"department"`
+	Attributes    List   `db:"attributes" csv:"attributes" bigquery:"attributes"`
+	Restrictions  string `db:"restrictions" csv:"restrictions" bigquery:"restrictions"`
+	Prerequisites string `db:"prerequisites" csv:"prerequisites" bigquery:"prerequisites"`
+	Corequisites  string `db:"corequisites" csv:"corequisites" bigquery:"corequisites"`
+}
+
+// List is a list of values from the catalog, written as "a, b" in reports
+type List []string
+
+func (l List) String() string {
+	return strings.Join(l, ", ")
+}
+
+func (l List) MarshalCSV() (string, error) {
+	return l.String(), nil
+}
+
+// ErrNotInCatalog is returned for courses the catalog doesn't list
+var ErrNotInCatalog = errors.New("course is not in the catalog")
+
+// GetCatalogEntry scrapes the catalog entry of a course (e.g. COP2220) in a
+// term
+func GetCatalogEntry(c *colly.Collector, name string, term Term) (CatalogEntry, error) {
+	entry := CatalogEntry{Name: name, Term: term}
+	if err := Current.require(CatalogPage); err != nil {
+		return entry, err
+	}
+	termId, err := term.Id()
+	if err != nil {
+		return entry, err
+	}
+	if len(name) < 4 {
+		return entry, fmt.Errorf("%s is not a valid course", name)
+	}
+
+	found := false
+	c.OnHTML(".pagebodydiv > table.datadisplaytable", func(e *colly.HTMLElement) {
+		title := strings.TrimSpace(e.DOM.Find("td.nttitle").First().Text())
+		if title == "" {
+			return
+		}
+		found = true
+		if split := strings.SplitN(title, " - ", 2); len(split) == 2 {
+			title = split[1]
+		}
+		entry.Title = strings.TrimSpace(title)
+
+		// The details are lines of text separated by <br>s, some of them
+		// under labels like "Levels:"
+		body, _ := e.DOM.Find("td.ntdefault").First().Html()
+		body = strings.ReplaceAll(body, "\n", " ")
+		body = regexp.MustCompile(`(?i)<br\s*/?>`).ReplaceAllString(body, "\n")
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+		if err != nil {
+			return
+		}
+
+		creditsR := regexp.MustCompile(`^(\d+\.\d+(?:\s+(?:TO|OR)\s+\d+\.\d+)?)\s+Credit hours`)
+		var description []string
+		label := ""
+		values := make(map[string][]string)
+		for _, line := range strings.Split(doc.Text(), "\n") {
+			line = strings.Join(strings.Fields(line), " ")
+			if i := strings.Index(line, ":"); i > 0 && isCatalogLabel(line[:i]) {
+				label = line[:i]
+				line = strings.TrimSpace(line[i+1:])
+			}
+			switch {
+			case line == "":
+				if len(values[label]) > 0 {
+					label = "" // a blank line ends a labelled section
+				}
+			case label != "":
+				values[label] = append(values[label], line)
+			case creditsR.MatchString(line):
+				entry.Credits = creditsR.FindStringSubmatch(line)[1]
+			case strings.HasSuffix(line, " hours"):
+				// lecture and lab hours duplicate the credits
+			case strings.HasSuffix(line, " Department"):
+				entry.Department = line
+			case entry.Credits == "":
+				description = append(description, line)
+			}
+		}
+		entry.Description = strings.Join(description, " ")
+		entry.Levels = splitList(values["Levels"])
+		entry.ScheduleTypes = splitList(values["Schedule Types"])
+		entry.Attributes = splitList(values["Course Attributes"])
+		entry.Restrictions = strings.Join(values["Restrictions"], " ")
+		entry.Prerequisites = strings.Join(values["Prerequisites"], " ")
+		entry.Corequisites = strings.Join(values["Corequisites"], " ")
+	})
+
+	err = c.Visit(fmt.Sprintf("%vbwckctlg.p_disp_course_detail?cat_term_in=%d&subj_code_in=%v&crse_numb_in=%v",
+		Current.BannerUrl, termId, url.QueryEscape(name[0:3]), url.QueryEscape(name[3:])))
+	if err == nil && !found {
+		err = ErrNotInCatalog
+	}
+	return entry, err
+}
+
+// isCatalogLabel reports whether text labels a section of a catalog entry
+func isCatalogLabel(text string) bool {
+	switch text {
+	case "Levels", "Schedule Types", "Course Attributes", "Restrictions", "Prerequisites", "Corequisites":
+		return true
+	}
+	return false
+}
+
+// splitList splits the comma separated lines of a catalog section
+func splitList(lines []string) List {
+	var list List
+	for _, line := range lines {
+		for _, item := range strings.Split(line, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
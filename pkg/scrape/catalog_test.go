@@ -0,0 +1,77 @@
+package scrape
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// emptyPage is a Banner page without any rows
+const emptyPage = `<html><body><div class="pagebodydiv"><table class="datadisplaytable"></table></div></body></html>`
+
+// newTestBanner points the current institution at a stub of Banner until the
+// test ends
+func newTestBanner(t *testing.T, handler http.HandlerFunc) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	institution := Current
+	Current.BannerUrl = srv.URL + "/"
+	t.Cleanup(func() { Current = institution })
+}
+
+func TestGetCatalogEntry(t *testing.T) {
+	// Serves a saved course detail page for COP 2220, and an empty one for
+	// any other course
+	newTestBanner(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("subj_code_in") == "COP" && r.URL.Query().Get("crse_numb_in") == "2220" {
+			http.ServeFile(w, r, "testdata/course_detail.html")
+			return
+		}
+		_, _ = w.Write([]byte(emptyPage))
+	})
+
+	tests := []struct {
+		name string
+		want CatalogEntry
+		err  error
+	}{
+		{
+			name: "COP2220",
+			want: CatalogEntry{
+				Name:  "COP2220",
+				Term:  "Fall 2023",
+				Title: "Computer Science I",
+				Description: "An introduction to programming in C, covering data types, control structures, " +
+					"functions, arrays & pointers, and the design of small programs.",
+				Credits:       "3.000",
+				Levels:        List{"Undergraduate", "Graduate"},
+				ScheduleTypes: List{"Lecture", "Laboratory"},
+				Department:    "School of Computing Department",
+				Attributes:    List{"Gordon Rule Computation", "Gen Ed Math"},
+				Restrictions:  "Must be enrolled in one of the following Levels: Undergraduate",
+				Prerequisites: "Undergraduate level MAC 1147 Minimum Grade of C or " +
+					"Undergraduate level MAC 1114 Minimum Grade of C",
+				Corequisites: "COP 2220L",
+			},
+		},
+		{
+			name: "COP9999",
+			want: CatalogEntry{Name: "COP9999", Term: "Fall 2023"},
+			err:  ErrNotInCatalog,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetCatalogEntry(colly.NewCollector(), tt.name, "Fall 2023")
+			if err != tt.err {
+				t.Errorf("GetCatalogEntry() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCatalogEntry() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
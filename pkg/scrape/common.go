@@ -2,44 +2,46 @@ package scrape
 
 import (
 	"cloud.google.com/go/bigquery"
-	"errors"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
-const bannerUrl = "https://bannerssb.unf.edu/nfpo-ssb/"
-
-// TermToId takes a term string like "Fall 2017" and determines its
-// corresponding id (e.g: 201780)
-func TermToId(term string) (int, error) {
-	split := strings.Split(term, " ")
-
-	season := split[0]
-	year, err := strconv.Atoi(split[1])
-	if err != nil {
-		return 0, errors.New(term + " is not a valid term")
-	}
+// Classes of Banner pages, which go stale at different rates
+const (
+	IsqPage        = "isq"
+	SchedulePage   = "schedule"
+	DepartmentPage = "department"
+	CatalogPage    = "catalog"
+	SeatPage       = "seats"
+	OtherPage      = "other"
+)
 
-	var seasonSuffix int
-	switch season {
-	case "Spring":
-		seasonSuffix = 1
-	case "Summer":
-		seasonSuffix = 5
-	case "Fall":
-		seasonSuffix = 8
+// PageClass determines which class of Banner page a URL points to
+func PageClass(url string) string {
+	switch {
+	case strings.Contains(url, "isq_grade"):
+		return IsqPage
+	case strings.Contains(url, "p_disp_listcrse"):
+		return SchedulePage
+	case strings.Contains(url, "p_dept_schd"):
+		return DepartmentPage
+	case strings.Contains(url, "p_disp_detail_sched"):
+		return SeatPage
+	case strings.Contains(url, "bwckctlg.p_disp"):
+		return CatalogPage
 	default:
-		return 0, errors.New(term + " is not a valid term")
-	}
-
-	// After Spring 2014, the season digit is in the 10s place
-	if year >= 2014 && term != "Spring 2014" {
-		seasonSuffix *= 10
+		return OtherPage
 	}
+}
 
-	id := year*100 + seasonSuffix
-	return id, nil
+// TermToId takes a term string like "Fall 2017" and determines its
+// corresponding id (e.g: 201780)
+//
+// Deprecated: Use Term(term).Id(), which codes terms the way the current
+// institution does.
+func TermToId(term string) (int, error) {
+	return Term(term).Id()
 }
 
 func CollectScheduleParams(isqs []CourseIsq, grades []CourseGrades) []ScheduleParams {
@@ -59,7 +61,7 @@ func CollectScheduleParams(isqs []CourseIsq, grades []CourseGrades) []SchedulePa
 	for _, course := range courses {
 		subject := course.Name[0:3]
 		courseNumber := course.Name[3:]
-		term, err := TermToId(course.Term)
+		term, err := course.Term.Id()
 		if err != nil {
 			continue
 		}
@@ -23,19 +23,31 @@ type Meeting struct {
 
 type DeptSchedule struct {
 	Course
-	Status      bigquery.NullString `bigquery:"status"`
-	Title       string              `bigquery:"title"`
-	InstructorN bigquery.NullInt64  `bigquery:"instructor_n"`
-	Credits     int                 `bigquery:"credits"`
-	PartOfTerm  string              `bigquery:"part_of_term"`
-	Meetings    []Meeting           `bigquery:"meetings"`
-	Campus      string              `bigquery:"campus"`
-	WaitCount   int                 `bigquery:"wait_count"`
-	Approval    bigquery.NullString `bigquery:"approval"`
-	Department  int                 `bigquery:"department"`
+	Status      bigquery.NullString `bigquery:"status" db:"status"`
+	Title       string              `bigquery:"title" db:"title"`
+	InstructorN bigquery.NullInt64  `bigquery:"instructor_n" db:"instructor_n"`
+	Credits     int                 `bigquery:"credits" db:"credits"`
+	PartOfTerm  string              `bigquery:"part_of_term" db:"part_of_term"`
+	Meetings    []Meeting           `bigquery:"meetings" db:"meetings"`
+	Campus      string              `bigquery:"campus" db:"campus"`
+	WaitCount   int                 `bigquery:"wait_count" db:"wait_count"`
+	Approval    bigquery.NullString `bigquery:"approval" db:"approval"`
+	Department  int                 `bigquery:"department" db:"department"`
 }
 
-func GetDepartment(c *colly.Collector, term string, deptId int) ([]DeptSchedule, error) {
+// ScheduleVersion is a section of a department schedule as it was from
+// ValidFrom until ValidTo, which isn't set for the current version
+type ScheduleVersion struct {
+	DeptSchedule
+	ValidFrom time.Time              `bigquery:"valid_from" db:"valid_from"`
+	ValidTo   bigquery.NullTimestamp `bigquery:"valid_to" db:"valid_to"`
+}
+
+func GetDepartment(c *colly.Collector, term Term, deptId int) ([]DeptSchedule, error) {
+	if err := Current.require(DepartmentPage); err != nil {
+		return nil, err
+	}
+
 	var department []DeptSchedule
 
 	// Collect the data for each course listing in the department and term
@@ -58,7 +70,7 @@ func GetDepartment(c *colly.Collector, term string, deptId int) ([]DeptSchedule,
 			// Extract the begin and end date
 			var beginDate, endDate civil.Date
 			if strings.TrimSpace(cells.Eq(6-offset).Text()) != "" {
-				currYear := strings.Split(term, " ")[1]
+				currYear := strconv.Itoa(term.Year())
 				beginDateRaw, _ := time.Parse("01-02-2006", cells.Eq(6-offset).Text()+"-"+currYear)
 				endDateRaw, _ := time.Parse("01-02-2006", cells.Eq(7-offset).Text()+"-"+currYear)
 				beginDate = civil.DateOf(beginDateRaw)
@@ -152,8 +164,8 @@ func GetDepartment(c *colly.Collector, term string, deptId int) ([]DeptSchedule,
 		})
 	})
 
-	termId, _ := TermToId(term)
-	return department, c.Post(bannerUrl+"wksfwbs.p_dept_schd", map[string]string{
+	termId, _ := term.Id()
+	return department, c.Post(Current.BannerUrl+"wksfwbs.p_dept_schd", map[string]string{
 		"pv_term":   strconv.Itoa(termId),
 		"pv_dept":   strconv.Itoa(deptId),
 		"pv_ptrm":   "",
@@ -0,0 +1,176 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Institution is a school running Banner 8: where its pages are, how it
+// codes terms, and which classes of its pages can be scraped. The schedule
+// pages are standard Banner, while ISQs and department schedules come from
+// UNF's own wksfwbs package, so other schools' profiles leave them out. The
+// catalog and seats are standard Banner too.
+type Institution struct {
+	Name      string     `json:"name"`
+	BannerUrl string     `json:"banner_url"`
+	Terms     TermScheme `json:"terms"`
+	Pages     []string   `json:"pages"`
+}
+
+// TermScheme describes how an institution codes terms as ids, which are the
+// year followed by a two digit season code (e.g. 201780 for Fall 2017)
+type TermScheme struct {
+	// Seasons maps each season to its code
+	Seasons map[string]int `json:"seasons"`
+	// NextYear lists the seasons coded under the following year, for
+	// schools that code terms by academic year
+	NextYear []string `json:"next_year"`
+	// ShortThrough is the last term coded with a single digit season (the
+	// code divided by 10), for schools that have since changed schemes
+	ShortThrough Term `json:"short_through"`
+	// Parts lists the parts of term a season is split into, like the summer
+	// sessions A, B, and C. Parts share the id of their term.
+	Parts map[string][]string `json:"parts"`
+}
+
+// UNF is the University of North Florida
+var UNF = Institution{
+	Name:      "unf",
+	BannerUrl: "https://bannerssb.unf.edu/nfpo-ssb/",
+	Terms: TermScheme{
+		Seasons:      map[string]int{"Spring": 10, "Summer": 50, "Fall": 80},
+		ShortThrough: "Spring 2014",
+		Parts:        map[string][]string{"Summer": {"A", "B", "C"}},
+	},
+	Pages: []string{IsqPage, SchedulePage, DepartmentPage, CatalogPage, SeatPage},
+}
+
+// Institutions are the built-in institution profiles, by name
+var Institutions = map[string]Institution{
+	UNF.Name: UNF,
+}
+
+// Current is the institution being scraped
+var Current = UNF
+
+// LoadInstitution returns the built-in profile with the given name, or
+// reads one from a JSON file
+func LoadInstitution(nameOrFile string) (Institution, error) {
+	if institution, found := Institutions[nameOrFile]; found {
+		return institution, nil
+	}
+	var institution Institution
+	b, err := os.ReadFile(nameOrFile)
+	if os.IsNotExist(err) {
+		return institution, fmt.Errorf("unknown institution %s", nameOrFile)
+	} else if err != nil {
+		return institution, err
+	}
+	if err := json.Unmarshal(b, &institution); err != nil {
+		return institution, fmt.Errorf("failed to parse %s: %v", nameOrFile, err)
+	}
+	if institution.BannerUrl == "" || len(institution.Terms.Seasons) == 0 {
+		return institution, fmt.Errorf("%s needs a banner_url and term seasons", nameOrFile)
+	}
+	if !strings.HasSuffix(institution.BannerUrl, "/") {
+		institution.BannerUrl += "/"
+	}
+	return institution, nil
+}
+
+// Has reports whether a class of page can be scraped from the institution
+func (i Institution) Has(page string) bool {
+	for _, p := range i.Pages {
+		if p == page {
+			return true
+		}
+	}
+	return false
+}
+
+// require returns an error if a class of page can't be scraped from the
+// institution
+func (i Institution) require(page string) error {
+	if !i.Has(page) {
+		return fmt.Errorf("%s has no %s pages", i.Name, page)
+	}
+	return nil
+}
+
+// Id determines the id of a term like "Fall 2017"
+func (s TermScheme) Id(term Term) (int, error) {
+	year, code, err := s.parse(term)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.ShortThrough != "" {
+		lastYear, lastCode, err := s.parse(s.ShortThrough)
+		if err != nil {
+			return 0, err
+		}
+		if year < lastYear || (year == lastYear && code <= lastCode) {
+			code /= 10
+		}
+	}
+	return year*100 + code, nil
+}
+
+// Ordinal numbers the whole terms in order, so the difference between two
+// ordinals is how many terms apart they are. Parts share their term's.
+func (s TermScheme) Ordinal(term Term) (int, error) {
+	year, code, err := s.parse(term)
+	if err != nil {
+		return 0, err
+	}
+	var earlier int
+	for _, c := range s.Seasons {
+		if c < code {
+			earlier++
+		}
+	}
+	return year*len(s.Seasons) + earlier, nil
+}
+
+// parse splits a term into the year it's coded under and its season code
+func (s TermScheme) parse(term Term) (int, int, error) {
+	invalid := fmt.Errorf("%s is not a valid term", term)
+	split := strings.Split(string(term), " ")
+	if len(split) == 3 {
+		parts := s.Parts[split[0]]
+		if split[1] == "" || indexOf(parts, split[1]) == len(parts) {
+			return 0, 0, invalid
+		}
+		split = []string{split[0], split[2]}
+	}
+	if len(split) != 2 {
+		return 0, 0, invalid
+	}
+
+	season := split[0]
+	year, err := strconv.Atoi(split[1])
+	if err != nil {
+		return 0, 0, invalid
+	}
+	code, found := s.Seasons[season]
+	if !found {
+		return 0, 0, invalid
+	}
+	if s.nextYear(season) {
+		year++
+	}
+	return year, code, nil
+}
+
+// nextYear reports whether a season is coded under the following year
+func (s TermScheme) nextYear(season string) bool {
+	for _, next := range s.NextYear {
+		if next == season {
+			return true
+		}
+	}
+	return false
+}
@@ -38,6 +38,10 @@ type CourseGrades struct {
 }
 
 func GetIsqAndGrades(c *colly.Collector, name string, isProfessor bool) ([]CourseIsq, []CourseGrades, error) {
+	if err := Current.require(IsqPage); err != nil {
+		return nil, nil, err
+	}
+
 	var isqs []CourseIsq
 	var grades []CourseGrades
 
@@ -62,7 +66,7 @@ func GetIsqAndGrades(c *colly.Collector, name string, isProfessor bool) ([]Cours
 
 			course := Course{
 				Name:       courseID,
-				Term:       cells.Eq(0).Text(),
+				Term:       Term(cells.Eq(0).Text()),
 				Crn:        atoi(cells.Eq(1).Text()),
 				Instructor: nullString(instructor),
 			}
@@ -112,7 +116,7 @@ func GetIsqAndGrades(c *colly.Collector, name string, isProfessor bool) ([]Cours
 
 			course := Course{
 				Name:       courseID,
-				Term:       cells.Eq(0).Text(),
+				Term:       Term(cells.Eq(0).Text()),
 				Crn:        atoi(cells.Eq(1).Text()),
 				Instructor: nullString(instructor),
 			}
@@ -130,9 +134,9 @@ func GetIsqAndGrades(c *colly.Collector, name string, isProfessor bool) ([]Cours
 
 	var url string
 	if isProfessor {
-		url = bannerUrl + "wksfwbs.p_instructor_isq_grade?pv_instructor=" + name
+		url = Current.BannerUrl + "wksfwbs.p_instructor_isq_grade?pv_instructor=" + name
 	} else {
-		url = bannerUrl + "wksfwbs.p_course_isq_grade?pv_course_id=" + name
+		url = Current.BannerUrl + "wksfwbs.p_course_isq_grade?pv_course_id=" + name
 	}
 
 	return isqs, grades, c.Visit(url)
@@ -33,6 +33,10 @@ type ScheduleParams struct {
 }
 
 func GetSchedules(c *colly.Collector, params []ScheduleParams) ([]CourseSchedule, error) {
+	if err := Current.require(SchedulePage); err != nil {
+		return nil, err
+	}
+
 	var schedules []CourseSchedule
 
 	// Collect the schedules
@@ -54,7 +58,7 @@ func GetSchedules(c *colly.Collector, params []ScheduleParams) ([]CourseSchedule
 
 			course := Course{
 				Name: strings.Replace(headerData[2], " ", "", 1),
-				Term: term,
+				Term: Term(term),
 				Crn:  atoi(headerData[1]),
 			}
 
@@ -129,7 +133,7 @@ func GetSchedules(c *colly.Collector, params []ScheduleParams) ([]CourseSchedule
 	for _, p := range params {
 		url := fmt.Sprintf(
 			"%vbwckctlg.p_disp_listcrse?schd_in=&subj_in=%v&crse_in=%v&term_in=%d",
-			bannerUrl, p.Subject, p.CourseNumber, p.TermId)
+			Current.BannerUrl, p.Subject, p.CourseNumber, p.TermId)
 		err = c.Visit(url)
 		if err != nil {
 			return nil, err
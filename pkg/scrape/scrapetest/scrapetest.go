@@ -0,0 +1,100 @@
+// Package scrapetest builds scraped rows for tests, so the packages that
+// store and report on them share the same fixtures
+package scrapetest
+
+import (
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+// Term is the term of the sections built here
+const Term scrape.Term = "Fall 2023"
+
+// Department is the department of the sections built here
+const Department = 6502
+
+// The first and last day of classes in Term
+var (
+	TermStart = civil.Date{Year: 2023, Month: 8, Day: 21}
+	TermEnd   = civil.Date{Year: 2023, Month: 12, Day: 8}
+)
+
+// Course makes a section of a course in Term, with no instructor if it's ""
+func Course(name string, crn int, instructor string) scrape.Course {
+	return scrape.Course{
+		Name:       name,
+		Term:       Term,
+		Crn:        crn,
+		Instructor: bigquery.NullString{StringVal: instructor, Valid: instructor != ""},
+	}
+}
+
+// Isq makes the ISQ of a section from how many of its enrolled students
+// responded and the percent of responses rating it 5 down to 1
+func Isq(c scrape.Course, enrolled, responded int, percents [5]float64) scrape.CourseIsq {
+	isq := scrape.Isq{
+		Enrolled:  enrolled,
+		Responded: responded,
+		Percent5:  percents[0],
+		Percent4:  percents[1],
+		Percent3:  percents[2],
+		Percent2:  percents[3],
+		Percent1:  percents[4],
+	}
+	if enrolled > 0 {
+		isq.ResponseRate = float64(responded) / float64(enrolled) * 100
+	}
+	for i, percent := range percents {
+		isq.Rating += float64(5-i) * percent / 100
+	}
+	return scrape.CourseIsq{Course: c, Isq: isq}
+}
+
+// Grades makes the grades of a section with an average GPA and the percent
+// of students given a D or F
+func Grades(c scrape.Course, average, percentD, percentF float64) scrape.CourseGrades {
+	return scrape.CourseGrades{
+		Course: c,
+		Grades: scrape.Grades{PercentD: percentD, PercentF: percentF, Average: average},
+	}
+}
+
+// Meeting makes a weekly meeting in a room of building 15 between two dates.
+// Times are like 1030 for 10:30.
+func Meeting(days string, begin, end int, room int64, from, to civil.Date) scrape.Meeting {
+	return scrape.Meeting{
+		Type:      "LEC",
+		BeginDate: from,
+		EndDate:   to,
+		Days:      bigquery.NullString{StringVal: days, Valid: true},
+		BeginTime: bigquery.NullTime{Time: civil.Time{Hour: begin / 100, Minute: begin % 100}, Valid: true},
+		EndTime:   bigquery.NullTime{Time: civil.Time{Hour: end / 100, Minute: end % 100}, Valid: true},
+		Building:  bigquery.NullString{StringVal: "15", Valid: true},
+		Room:      bigquery.NullInt64{Int64: room, Valid: true},
+	}
+}
+
+// Section makes a section of Department's schedule meeting at the given
+// times
+func Section(c scrape.Course, meetings ...scrape.Meeting) scrape.DeptSchedule {
+	return scrape.DeptSchedule{
+		Course:     c,
+		Title:      "Computer Science I",
+		Credits:    3,
+		Meetings:   meetings,
+		Department: Department,
+	}
+}
+
+// Version makes a version of a section valid from one time until another,
+// or still current if to is zero
+func Version(s scrape.DeptSchedule, from, to time.Time) scrape.ScheduleVersion {
+	v := scrape.ScheduleVersion{DeptSchedule: s, ValidFrom: from}
+	if !to.IsZero() {
+		v.ValidTo = bigquery.NullTimestamp{Timestamp: to, Valid: true}
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package scrape
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// Seats is the registration availability of a section
+type Seats struct {
+	Capacity          int `db:"capacity" csv:"capacity" bigquery:"capacity"`
+	Actual            int `db:"actual" csv:"actual" bigquery:"actual"`
+	Remaining         int `db:"remaining" csv:"remaining" bigquery:"remaining"`
+	WaitlistCapacity  int `db:"waitlist_capacity" csv:"waitlist_capacity" bigquery:"waitlist_capacity"`
+	WaitlistActual    int `db:"waitlist_actual" csv:"waitlist_actual" bigquery:"waitlist_actual"`
+	WaitlistRemaining int `db:"waitlist_remaining" csv:"waitlist_remaining" bigquery:"waitlist_remaining"`
+}
+
+// SectionSnapshot is the seats of a section at the time it was taken
+type SectionSnapshot struct {
+	Course
+	Seats
+	Department int       `db:"department" csv:"department" bigquery:"department"`
+	TakenAt    time.Time `db:"taken_at" csv:"taken_at" bigquery:"taken_at"`
+}
+
+// GetSeats scrapes the registration availability of a section
+func GetSeats(c *colly.Collector, term Term, crn int) (Seats, error) {
+	var seats Seats
+	if err := Current.require(SeatPage); err != nil {
+		return seats, err
+	}
+	termId, err := term.Id()
+	if err != nil {
+		return seats, err
+	}
+
+	found := false
+	c.OnHTML("table.datadisplaytable tr", func(e *colly.HTMLElement) {
+		cells := e.DOM.Find("td")
+		if cells.Size() < 3 {
+			return
+		}
+		counts := make([]int, 3)
+		cells.Each(func(i int, s *goquery.Selection) {
+			if i < 3 {
+				counts[i] = atoi(strings.TrimSpace(s.Text()))
+			}
+		})
+		switch strings.TrimSpace(e.DOM.Find("th").First().Text()) {
+		case "Seats":
+			seats.Capacity, seats.Actual, seats.Remaining = counts[0], counts[1], counts[2]
+			found = true
+		case "Waitlist Seats":
+			seats.WaitlistCapacity, seats.WaitlistActual, seats.WaitlistRemaining = counts[0], counts[1], counts[2]
+		}
+	})
+
+	err = c.Visit(fmt.Sprintf("%vbwckschd.p_disp_detail_sched?term_in=%d&crn_in=%d",
+		Current.BannerUrl, termId, crn))
+	if err == nil && !found {
+		err = fmt.Errorf("no seats listed for %d in %s", crn, term)
+	}
+	return seats, err
+}
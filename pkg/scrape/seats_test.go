@@ -0,0 +1,62 @@
+package scrape
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func TestGetSeats(t *testing.T) {
+	// Serves a saved detail page for CRN 10001 in Fall 2023, the same page
+	// without its waitlist for CRN 10002, and an empty one for any other
+	newTestBanner(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("term_in") != "202380" {
+			_, _ = w.Write([]byte(emptyPage))
+			return
+		}
+		switch r.URL.Query().Get("crn_in") {
+		case "10001":
+			http.ServeFile(w, r, "testdata/detail_sched.html")
+		case "10002":
+			_, _ = w.Write([]byte(`<html><body><div class="pagebodydiv"><table class="datadisplaytable">
+				<tr><th></th><th>Capacity</th><th>Actual</th><th>Remaining</th></tr>
+				<tr><th>Seats</th><td>25</td><td>25</td><td>0</td></tr>
+				</table></div></body></html>`))
+		default:
+			_, _ = w.Write([]byte(emptyPage))
+		}
+	})
+
+	tests := []struct {
+		name    string
+		term    Term
+		crn     int
+		want    Seats
+		wantErr string
+	}{
+		{"with waitlist", "Fall 2023", 10001, Seats{40, 38, 2, 10, 3, 7}, ""},
+		{"without waitlist", "Fall 2023", 10002, Seats{Capacity: 25, Actual: 25}, ""},
+		{"not listed", "Fall 2023", 10003, Seats{}, "no seats listed"},
+		{"other term", "Spring 2024", 10001, Seats{}, "no seats listed"},
+		{"invalid term", "Winter 2023", 10001, Seats{}, "not a valid term"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetSeats(colly.NewCollector(), tt.term, tt.crn)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("GetSeats() error = %v, want %q", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetSeats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Seats are standard Banner, but a profile may still leave them out
+	Current.Pages = []string{SchedulePage}
+	if _, err := GetSeats(colly.NewCollector(), "Fall 2023", 10001); err == nil {
+		t.Error("GetSeats() succeeded without seat pages, want an error")
+	}
+}
@@ -0,0 +1,191 @@
+package scrape
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Term is an academic term like "Fall 2017", or a part of one like
+// "Summer A 2017". Terms are compared and converted to ids using the current
+// institution's TermScheme.
+type Term string
+
+// ParseTerm parses a term, normalizing its spacing and capitalization
+func ParseTerm(s string) (Term, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 && len(fields) != 3 {
+		return "", fmt.Errorf("%s is not a valid term", s)
+	}
+	for i := range fields[:len(fields)-1] {
+		fields[i] = strings.Title(strings.ToLower(fields[i]))
+	}
+	if len(fields) == 3 {
+		fields[1] = strings.ToUpper(fields[1])
+	}
+	t := Term(strings.Join(fields, " "))
+	if _, err := t.Id(); err != nil {
+		return "", fmt.Errorf("%s is not a valid term", strings.TrimSpace(s))
+	}
+	return t, nil
+}
+
+// NewTerm makes the term for a season and year, like NewTerm("Fall", 2017)
+func NewTerm(season string, year int) Term {
+	return Term(season + " " + strconv.Itoa(year))
+}
+
+// IdToTerm determines the term with an id at the current institution (e.g.
+// "Fall 2017" for 201780)
+func IdToTerm(id int) (Term, error) {
+	s := Current.Terms
+	for season := range s.Seasons {
+		year := id / 100
+		if s.nextYear(season) {
+			year--
+		}
+		t := NewTerm(season, year)
+		if tid, err := t.Id(); err == nil && tid == id {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%d is not a valid term id", id)
+}
+
+// Season is the season of the term, like "Fall"
+func (t Term) Season() string {
+	return strings.Fields(string(t) + " ")[0]
+}
+
+// Part is the part of term, like "A" for "Summer A 2017", or "" for the
+// whole term
+func (t Term) Part() string {
+	fields := strings.Fields(string(t))
+	if len(fields) != 3 {
+		return ""
+	}
+	return fields[1]
+}
+
+// Year is the calendar year the term falls in, or 0 if it's invalid
+func (t Term) Year() int {
+	fields := strings.Fields(string(t))
+	if len(fields) == 0 {
+		return 0
+	}
+	year, _ := strconv.Atoi(fields[len(fields)-1])
+	return year
+}
+
+// Base is the whole term a part of term belongs to
+func (t Term) Base() Term {
+	if t.Part() == "" {
+		return t
+	}
+	return NewTerm(t.Season(), t.Year())
+}
+
+// Id determines the term's id at the current institution (e.g. 201780).
+// Parts of a term share its id.
+func (t Term) Id() (int, error) {
+	return Current.Terms.Id(t)
+}
+
+// Ordinal numbers the term among the whole terms at the current institution
+// (e.g. Fall 2017 is one after Summer 2017)
+func (t Term) Ordinal() (int, error) {
+	return Current.Terms.Ordinal(t)
+}
+
+// Valid reports whether the term has an id at the current institution
+func (t Term) Valid() bool {
+	_, err := t.Id()
+	return err == nil
+}
+
+func (t Term) String() string {
+	return string(t)
+}
+
+// Compare returns -1, 0, or 1 as t is before, the same as, or after u. Whole
+// terms come before their parts, and invalid terms before valid ones.
+func (t Term) Compare(u Term) int {
+	a, errA := t.Id()
+	b, errB := u.Id()
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(string(t), string(u))
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	case a != b:
+		if a < b {
+			return -1
+		}
+		return 1
+	}
+	parts := Current.Terms.Parts[t.Season()]
+	pa, pb := indexOf(parts, t.Part()), indexOf(parts, u.Part())
+	if pa != pb {
+		if pa < pb {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// Before reports whether t comes before u
+func (t Term) Before(u Term) bool {
+	return t.Compare(u) < 0
+}
+
+func (t Term) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+// UnmarshalText parses and validates a term, as in JSON and CSV
+func (t *Term) UnmarshalText(b []byte) error {
+	term, err := ParseTerm(string(b))
+	if err != nil {
+		return err
+	}
+	*t = term
+	return nil
+}
+
+// Value stores a term in SQL as its string
+func (t Term) Value() (driver.Value, error) {
+	return string(t), nil
+}
+
+// Scan reads a term stored in SQL
+func (t *Term) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		*t = Term(src)
+	case []byte:
+		*t = Term(src)
+	case nil:
+		*t = ""
+	default:
+		return fmt.Errorf("cannot scan %T into a term", src)
+	}
+	return nil
+}
+
+// indexOf is the position of a part in the parts of a term, with the whole
+// term ("") first
+func indexOf(parts []string, part string) int {
+	if part == "" {
+		return -1
+	}
+	for i, p := range parts {
+		if p == part {
+			return i
+		}
+	}
+	return len(parts)
+}
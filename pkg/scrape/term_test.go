@@ -0,0 +1,146 @@
+package scrape
+
+import "testing"
+
+func TestParseTerm(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Term
+		err  bool
+	}{
+		{"Fall 2017", "Fall 2017", false},
+		{"  fall   2017 ", "Fall 2017", false},
+		{"SPRING 2014", "Spring 2014", false},
+		{"summer a 2023", "Summer A 2023", false},
+		{"Summer C 2023", "Summer C 2023", false},
+		{"Summer D 2023", "", true},
+		{"Fall A 2023", "", true},
+		{"Winter 2023", "", true},
+		{"Fall", "", true},
+		{"Fall twenty", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTerm(tt.in)
+			if (err != nil) != tt.err {
+				t.Fatalf("ParseTerm() error = %v, want error %t", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTerm() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermId(t *testing.T) {
+	tests := []struct {
+		term Term
+		id   int
+	}{
+		{"Spring 2014", 201401},
+		{"Summer 2013", 201305},
+		{"Summer 2014", 201450},
+		{"Fall 2017", 201780},
+		{"Summer A 2023", 202350},
+		{"Summer B 2023", 202350},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.term), func(t *testing.T) {
+			id, err := tt.term.Id()
+			if err != nil || id != tt.id {
+				t.Fatalf("Id() = %d, %v, want %d", id, err, tt.id)
+			}
+			if id, err := TermToId(string(tt.term)); err != nil || id != tt.id {
+				t.Errorf("TermToId() = %d, %v, want %d", id, err, tt.id)
+			}
+
+			// Parts share their term's id, so the id round trips to the base
+			back, err := IdToTerm(id)
+			if err != nil || back != tt.term.Base() {
+				t.Errorf("IdToTerm(%d) = %q, %v, want %q", id, back, err, tt.term.Base())
+			}
+		})
+	}
+
+	if _, err := IdToTerm(201799); err == nil {
+		t.Error("IdToTerm(201799) succeeded, want an error")
+	}
+}
+
+func TestTermIdAcademicYear(t *testing.T) {
+	defer func() { Current = UNF }()
+	Current.Terms = TermScheme{
+		Seasons:  map[string]int{"Fall": 10, "Spring": 20, "Summer": 30},
+		NextYear: []string{"Fall"},
+	}
+
+	tests := []struct {
+		term Term
+		id   int
+	}{
+		{"Fall 2023", 202410},
+		{"Spring 2024", 202420},
+		{"Summer 2024", 202430},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.term), func(t *testing.T) {
+			id, err := tt.term.Id()
+			if err != nil || id != tt.id {
+				t.Fatalf("Id() = %d, %v, want %d", id, err, tt.id)
+			}
+			back, err := IdToTerm(id)
+			if err != nil || back != tt.term {
+				t.Errorf("IdToTerm(%d) = %q, %v, want %q", id, back, err, tt.term)
+			}
+		})
+	}
+}
+
+func TestTermCompare(t *testing.T) {
+	tests := []struct {
+		a, b Term
+		want int
+	}{
+		{"Spring 2014", "Fall 2013", 1},
+		{"Spring 2014", "Summer 2014", -1},
+		{"Fall 2017", "Fall 2017", 0},
+		{"Summer 2023", "Summer A 2023", -1},
+		{"Summer A 2023", "Summer B 2023", -1},
+		{"Summer C 2023", "Summer B 2023", 1},
+		{"Summer C 2023", "Fall 2023", -1},
+		{"bogus", "Fall 2023", -1},
+		{"Fall 2023", "bogus", 1},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%q.Compare(%q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Compare(tt.a); got != -tt.want {
+			t.Errorf("%q.Compare(%q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestTermOrdinal(t *testing.T) {
+	tests := []struct {
+		a, b Term
+		want int // terms from a to b
+	}{
+		{"Spring 2014", "Summer 2014", 1},
+		{"Fall 2013", "Spring 2014", 1},
+		{"Fall 2017", "Fall 2018", 3},
+		{"Summer 2023", "Summer B 2023", 0},
+		{"Spring 2023", "Fall 2021", -4},
+	}
+	for _, tt := range tests {
+		a, errA := tt.a.Ordinal()
+		b, errB := tt.b.Ordinal()
+		if errA != nil || errB != nil || b-a != tt.want {
+			t.Errorf("%q to %q is %d terms (%v, %v), want %d", tt.a, tt.b, b-a, errA, errB, tt.want)
+		}
+	}
+	if _, err := Term("Winter 2023").Ordinal(); err == nil {
+		t.Error(`"Winter 2023".Ordinal() succeeded, want an error`)
+	}
+}
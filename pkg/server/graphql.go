@@ -0,0 +1,491 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/openswoop/isqool/pkg/database"
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// NewGraphql returns a GraphQL endpoint over the data in a database.
+// Sections are loaded a level at a time, so a query costs one round of
+// queries per level of nesting rather than one per object.
+func NewGraphql(db database.Database) http.Handler {
+	s := graphql.MustParseSchema(schema, &queryResolver{db}, graphql.UseFieldResolvers())
+	return &relay.Handler{Schema: s}
+}
+
+type queryResolver struct {
+	db database.Database
+}
+
+func (q *queryResolver) Course(args struct{ Name string }) *courseResolver {
+	return &courseResolver{args.Name, newBatch(q.db, []string{args.Name}, false)}
+}
+
+func (q *queryResolver) Instructor(args struct{ Name string }) *instructorResolver {
+	return &instructorResolver{args.Name, newBatch(q.db, []string{args.Name}, true)}
+}
+
+func (q *queryResolver) Department(args struct {
+	Id   int32
+	Term string
+}) ([]*deptSectionResolver, error) {
+	term, err := scrape.ParseTerm(args.Term)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := q.db.LoadDepartments(int(args.Id), []scrape.Term{term})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, schedule := range schedules {
+		names = append(names, schedule.Name)
+	}
+	courses := newBatch(q.db, names, false)
+
+	var sections []*deptSectionResolver
+	for _, schedule := range schedules {
+		sections = append(sections, &deptSectionResolver{schedule, courses})
+	}
+	return sections, nil
+}
+
+func (q *queryResolver) Terms() ([]string, error) {
+	terms, err := q.db.Terms()
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].Before(terms[j])
+	})
+	names := make([]string, len(terms))
+	for i, term := range terms {
+		names[i] = string(term)
+	}
+	return names, err
+}
+
+func (q *queryResolver) Departments() ([]int32, error) {
+	ids, err := q.db.Departments()
+	departments := make([]int32, len(ids))
+	for i, id := range ids {
+		departments[i] = int32(id)
+	}
+	return departments, err
+}
+
+// batch is a group of sibling courses or instructors whose sections are
+// loaded together the first time any of them is resolved
+type batch struct {
+	db          database.Database
+	names       []string
+	instructors bool
+
+	once     sync.Once
+	sections map[string][]*sectionResolver
+	err      error
+}
+
+func newBatch(db database.Database, names []string, instructors bool) *batch {
+	return &batch{db: db, names: unique(names), instructors: instructors}
+}
+
+// load returns the sections of name, loading those of the whole batch
+func (b *batch) load(name string) ([]*sectionResolver, error) {
+	b.once.Do(func() {
+		var isqs []scrape.CourseIsq
+		var grades []scrape.CourseGrades
+		if b.instructors {
+			isqs, grades, b.err = b.db.LoadInstructors(b.names)
+		} else {
+			isqs, b.err = b.db.LoadIsqs(b.names)
+			if b.err == nil {
+				grades, b.err = b.db.LoadGrades(b.names)
+			}
+		}
+		if b.err != nil {
+			return
+		}
+
+		b.sections = make(map[string][]*sectionResolver)
+		for _, section := range newSections(b.db, isqs, grades) {
+			key := section.course.Name
+			if b.instructors {
+				key = section.course.Instructor.StringVal
+			}
+			b.sections[key] = append(b.sections[key], section)
+		}
+	})
+	return b.sections[name], b.err
+}
+
+// group holds the batches shared by a level of sections
+type group struct {
+	courses     *batch
+	instructors *batch
+
+	once      sync.Once
+	schedules map[sectionKey]*scrape.Schedule
+	err       error
+}
+
+type sectionKey struct {
+	Name string
+	Term scrape.Term
+	Crn  int
+}
+
+// newSections joins ISQs and grades into sections, most recent first
+func newSections(db database.Database, isqs []scrape.CourseIsq, grades []scrape.CourseGrades) []*sectionResolver {
+	var sections []*sectionResolver
+	byKey := make(map[sectionKey]*sectionResolver)
+	find := func(course scrape.Course) *sectionResolver {
+		key := sectionKey{course.Name, course.Term, course.Crn}
+		if section, ok := byKey[key]; ok {
+			return section
+		}
+		section := &sectionResolver{course: course}
+		byKey[key] = section
+		sections = append(sections, section)
+		return section
+	}
+	for i := range isqs {
+		find(isqs[i].Course).isq = &isqs[i].Isq
+	}
+	for i := range grades {
+		find(grades[i].Course).grades = &grades[i].Grades
+	}
+
+	var names, instructors []string
+	for _, section := range sections {
+		names = append(names, section.course.Name)
+		if section.course.Instructor.Valid {
+			instructors = append(instructors, section.course.Instructor.StringVal)
+		}
+	}
+	g := &group{courses: newBatch(db, names, false), instructors: newBatch(db, instructors, true)}
+	for _, section := range sections {
+		section.group = g
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		if c := sections[i].course.Term.Compare(sections[j].course.Term); c != 0 {
+			return c > 0
+		}
+		if sections[i].course.Name != sections[j].course.Name {
+			return sections[i].course.Name < sections[j].course.Name
+		}
+		return sections[i].course.Crn < sections[j].course.Crn
+	})
+	return sections
+}
+
+// schedule returns the schedule of a section, loading those of the whole
+// group
+func (g *group) schedule(key sectionKey) (*scrape.Schedule, error) {
+	g.once.Do(func() {
+		var schedules []scrape.CourseSchedule
+		schedules, g.err = g.courses.db.LoadSchedules(g.courses.names)
+		g.schedules = make(map[sectionKey]*scrape.Schedule)
+		for i := range schedules {
+			course := schedules[i].Course
+			g.schedules[sectionKey{course.Name, course.Term, course.Crn}] = &schedules[i].Schedule
+		}
+	})
+	return g.schedules[key], g.err
+}
+
+type courseResolver struct {
+	name  string
+	batch *batch
+}
+
+func (c *courseResolver) Name() string {
+	return c.name
+}
+
+func (c *courseResolver) Sections(args struct{ Term *string }) ([]*sectionResolver, error) {
+	sections, err := c.batch.load(c.name)
+	if err != nil {
+		return nil, err
+	}
+	return filterTerm(sections, args.Term)
+}
+
+func (c *courseResolver) Instructors() ([]*instructorResolver, error) {
+	sections, err := c.batch.load(c.name)
+	var instructors []*instructorResolver
+	seen := make(map[string]bool)
+	for _, section := range sections {
+		if instructor := section.Instructor(); instructor != nil && !seen[instructor.name] {
+			instructors = append(instructors, instructor)
+			seen[instructor.name] = true
+		}
+	}
+	return instructors, err
+}
+
+type instructorResolver struct {
+	name  string
+	batch *batch
+}
+
+func (i *instructorResolver) Name() string {
+	return i.name
+}
+
+func (i *instructorResolver) Sections(args struct{ Term *string }) ([]*sectionResolver, error) {
+	sections, err := i.batch.load(i.name)
+	if err != nil {
+		return nil, err
+	}
+	return filterTerm(sections, args.Term)
+}
+
+func (i *instructorResolver) Courses() ([]*courseResolver, error) {
+	sections, err := i.batch.load(i.name)
+	var courses []*courseResolver
+	seen := make(map[string]bool)
+	for _, section := range sections {
+		if course := section.Course(); !seen[course.name] {
+			courses = append(courses, course)
+			seen[course.name] = true
+		}
+	}
+	return courses, err
+}
+
+type sectionResolver struct {
+	course scrape.Course
+	isq    *scrape.Isq
+	grades *scrape.Grades
+	group  *group
+}
+
+func (s *sectionResolver) Course() *courseResolver {
+	return &courseResolver{s.course.Name, s.group.courses}
+}
+
+func (s *sectionResolver) Term() string {
+	return string(s.course.Term)
+}
+
+func (s *sectionResolver) Crn() int32 {
+	return int32(s.course.Crn)
+}
+
+func (s *sectionResolver) Instructor() *instructorResolver {
+	if !s.course.Instructor.Valid {
+		return nil
+	}
+	return &instructorResolver{s.course.Instructor.StringVal, s.group.instructors}
+}
+
+func (s *sectionResolver) Isq() *isqResolver {
+	if s.isq == nil {
+		return nil
+	}
+	return &isqResolver{*s.isq}
+}
+
+func (s *sectionResolver) Grades() *scrape.Grades {
+	return s.grades
+}
+
+func (s *sectionResolver) Schedule() (*scrape.Schedule, error) {
+	return s.group.schedule(sectionKey{s.course.Name, s.course.Term, s.course.Crn})
+}
+
+// isqResolver resolves the counts of an ISQ as GraphQL Ints, and the rest
+// of its fields directly
+type isqResolver struct {
+	scrape.Isq
+}
+
+func (i *isqResolver) Enrolled() int32 {
+	return int32(i.Isq.Enrolled)
+}
+
+func (i *isqResolver) Responded() int32 {
+	return int32(i.Isq.Responded)
+}
+
+type deptSectionResolver struct {
+	schedule scrape.DeptSchedule
+	courses  *batch
+}
+
+func (d *deptSectionResolver) Course() *courseResolver {
+	return &courseResolver{d.schedule.Name, d.courses}
+}
+
+func (d *deptSectionResolver) Term() string {
+	return string(d.schedule.Term)
+}
+
+func (d *deptSectionResolver) Crn() int32 {
+	return int32(d.schedule.Crn)
+}
+
+func (d *deptSectionResolver) Instructor() *string {
+	return nullString(d.schedule.Instructor)
+}
+
+func (d *deptSectionResolver) InstructorN() *int32 {
+	return nullInt(d.schedule.InstructorN)
+}
+
+func (d *deptSectionResolver) Status() *string {
+	return nullString(d.schedule.Status)
+}
+
+func (d *deptSectionResolver) Title() string {
+	return d.schedule.Title
+}
+
+func (d *deptSectionResolver) Credits() int32 {
+	return int32(d.schedule.Credits)
+}
+
+func (d *deptSectionResolver) PartOfTerm() string {
+	return d.schedule.PartOfTerm
+}
+
+func (d *deptSectionResolver) Campus() string {
+	return d.schedule.Campus
+}
+
+func (d *deptSectionResolver) WaitCount() int32 {
+	return int32(d.schedule.WaitCount)
+}
+
+func (d *deptSectionResolver) Approval() *string {
+	return nullString(d.schedule.Approval)
+}
+
+func (d *deptSectionResolver) Department() int32 {
+	return int32(d.schedule.Department)
+}
+
+func (d *deptSectionResolver) Meetings() []*meetingResolver {
+	meetings := make([]*meetingResolver, len(d.schedule.Meetings))
+	for i := range d.schedule.Meetings {
+		meetings[i] = &meetingResolver{d.schedule.Meetings[i]}
+	}
+	return meetings
+}
+
+func (d *deptSectionResolver) Section() (*sectionResolver, error) {
+	sections, err := d.courses.load(d.schedule.Name)
+	for _, section := range sections {
+		if section.course.Term == d.schedule.Term && section.course.Crn == d.schedule.Crn {
+			return section, err
+		}
+	}
+	return nil, err
+}
+
+type meetingResolver struct {
+	meeting scrape.Meeting
+}
+
+func (m *meetingResolver) Type() string {
+	return m.meeting.Type
+}
+
+func (m *meetingResolver) BeginDate() *string {
+	return date(m.meeting.BeginDate)
+}
+
+func (m *meetingResolver) EndDate() *string {
+	return date(m.meeting.EndDate)
+}
+
+func (m *meetingResolver) Days() *string {
+	return nullString(m.meeting.Days)
+}
+
+func (m *meetingResolver) BeginTime() *string {
+	return nullTime(m.meeting.BeginTime)
+}
+
+func (m *meetingResolver) EndTime() *string {
+	return nullTime(m.meeting.EndTime)
+}
+
+func (m *meetingResolver) Building() *string {
+	return nullString(m.meeting.Building)
+}
+
+func (m *meetingResolver) Room() *int32 {
+	return nullInt(m.meeting.Room)
+}
+
+func filterTerm(sections []*sectionResolver, arg *string) ([]*sectionResolver, error) {
+	if arg == nil {
+		return sections, nil
+	}
+	term, err := scrape.ParseTerm(*arg)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []*sectionResolver
+	for _, section := range sections {
+		if section.course.Term == term {
+			filtered = append(filtered, section)
+		}
+	}
+	return filtered, nil
+}
+
+func unique(values []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !seen[value] {
+			result = append(result, value)
+			seen[value] = true
+		}
+	}
+	return result
+}
+
+func nullString(s bigquery.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.StringVal
+}
+
+func nullInt(i bigquery.NullInt64) *int32 {
+	if !i.Valid {
+		return nil
+	}
+	v := int32(i.Int64)
+	return &v
+}
+
+func nullTime(t bigquery.NullTime) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.String()
+	return &s
+}
+
+func date(d civil.Date) *string {
+	if d == (civil.Date{}) {
+		return nil
+	}
+	s := d.String()
+	return &s
+}
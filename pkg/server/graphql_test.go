@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openswoop/isqool/pkg/database"
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+func TestGraphql(t *testing.T) {
+	db := database.NewSqlite(filepath.Join(t.TempDir(), "isqool.db"))
+	defer db.Close()
+	smith := scrapetest.Course("COP2220", 10001, "Smith")
+	doe := scrapetest.Course("COP2220", 10002, "Doe")
+	if err := db.SaveIsqs([]scrape.CourseIsq{
+		scrapetest.Isq(smith, 30, 15, [5]float64{50, 50, 0, 0, 0}),
+		scrapetest.Isq(doe, 20, 10, [5]float64{0, 100, 0, 0, 0}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveGrades([]scrape.CourseGrades{scrapetest.Grades(smith, 3.1, 5, 2)}); err != nil {
+		t.Fatal(err)
+	}
+	meeting := scrapetest.Meeting("MW", 1030, 1145, 1200, scrapetest.TermStart, scrapetest.TermEnd)
+	if err := db.SaveDepartments([]scrape.DeptSchedule{scrapetest.Section(smith, meeting)}); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewGraphql(db))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "course sections",
+			query: `{ course(name: "COP2220") { sections { crn instructor { name } isq { enrolled rating } grades { average } } } }`,
+			want: `{"data": {"course": {"sections": [
+				{"crn": 10001, "instructor": {"name": "Smith"}, "isq": {"enrolled": 30, "rating": 4.5}, "grades": {"average": 3.1}},
+				{"crn": 10002, "instructor": {"name": "Doe"}, "isq": {"enrolled": 20, "rating": 4}, "grades": null}
+			]}}}`,
+		},
+		{
+			name:  "instructor courses",
+			query: `{ instructor(name: "Doe") { courses { name instructors { name } } } }`,
+			want:  `{"data": {"instructor": {"courses": [{"name": "COP2220", "instructors": [{"name": "Smith"}, {"name": "Doe"}]}]}}}`,
+		},
+		{
+			name:  "department",
+			query: `{ department(id: 6502, term: "Fall 2023") { crn meetings { days beginTime room } section { isq { responded } } } }`,
+			want: `{"data": {"department": [
+				{"crn": 10001, "meetings": [{"days": "MW", "beginTime": "10:30:00", "room": 1200}], "section": {"isq": {"responded": 15}}}
+			]}}`,
+		},
+		{
+			name:  "terms and departments",
+			query: `{ terms departments }`,
+			want:  `{"data": {"terms": ["Fall 2023"], "departments": [6502]}}`,
+		},
+		{
+			name:  "invalid term",
+			query: `{ department(id: 6502, term: "Fall") { crn } }`,
+			want:  `{"errors": [{"message": "Fall is not a valid term", "path": ["department"]}], "data": null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"query": tt.query})
+			resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var got, want interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				b, _ := json.Marshal(got)
+				t.Errorf("response = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/openswoop/isqool/pkg/database"
+	"github.com/openswoop/isqool/pkg/report"
+	"github.com/openswoop/isqool/pkg/scrape"
+)
+
+//go:embed openapi.json
+var openapi []byte
+
+var professorR = regexp.MustCompile(`^N\d{8}$`)
+
+// Server is an HTTP API over the data in a database. Courses, instructors,
+// and departments that aren't in the database yet are scraped on demand, and
+// are not found if Banner has nothing for them either.
+type Server struct {
+	db     database.Database
+	c      *colly.Collector
+	maxAge time.Duration
+	mux    *http.ServeMux
+
+	// When scrapes last found nothing, so they aren't repeated within maxAge
+	mu     sync.Mutex
+	misses map[string]time.Time
+}
+
+func New(db database.Database, c *colly.Collector, maxAge time.Duration) *Server {
+	s := &Server{db: db, c: c, maxAge: maxAge, mux: http.NewServeMux(), misses: make(map[string]time.Time)}
+	s.mux.HandleFunc("GET /api/courses/{course}", s.getCourse)
+	s.mux.HandleFunc("GET /api/instructors/{instructor}", s.getInstructor)
+	s.mux.HandleFunc("GET /api/departments", s.listDepartments)
+	s.mux.HandleFunc("GET /api/departments/{department}/terms/{term}", s.getDepartment)
+	s.mux.HandleFunc("GET /api/terms", s.listTerms)
+	s.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		s.write(w, r, "application/json", openapi)
+	})
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) getCourse(w http.ResponseWriter, r *http.Request) {
+	course := r.PathValue("course")
+	format, err := parseFormat(r)
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	courses := []string{course}
+	isqs, err := s.db.LoadIsqs(courses)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	grades, err := s.db.LoadGrades(courses)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	schedules, err := s.db.LoadSchedules(courses)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Scrape the course if we haven't seen it before
+	if len(isqs) == 0 && len(grades) == 0 && !s.missed(course) {
+		isqs, grades, schedules, err = s.scrapeCourse(course, false)
+		if err != nil {
+			s.error(w, http.StatusBadGateway, err)
+			return
+		}
+	}
+	if len(isqs) == 0 && len(grades) == 0 {
+		s.error(w, http.StatusNotFound, fmt.Errorf("no sections found for course %s", course))
+		return
+	}
+
+	s.encode(w, r, format, report.BuildCourseRows(report.CourseInput{
+		Isqs:      isqs,
+		Grades:    grades,
+		Schedules: schedules,
+	}))
+}
+
+func (s *Server) getInstructor(w http.ResponseWriter, r *http.Request) {
+	instructor := r.PathValue("instructor")
+	format, err := parseFormat(r)
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Instructors are stored by last name, so N#s always go to Banner
+	var isqs []scrape.CourseIsq
+	var grades []scrape.CourseGrades
+	var schedules []scrape.CourseSchedule
+	if professorR.MatchString(instructor) {
+		if !s.missed(instructor) {
+			isqs, grades, schedules, err = s.scrapeCourse(instructor, true)
+			if err != nil {
+				s.error(w, http.StatusBadGateway, err)
+				return
+			}
+		}
+	} else {
+		isqs, grades, err = s.db.LoadInstructors([]string{instructor})
+		if err != nil {
+			s.error(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if len(isqs) == 0 && len(grades) == 0 {
+		s.error(w, http.StatusNotFound, fmt.Errorf("no sections found for instructor %s", instructor))
+		return
+	}
+
+	s.encode(w, r, format, report.BuildCourseRows(report.CourseInput{
+		Isqs:      isqs,
+		Grades:    grades,
+		Schedules: schedules,
+	}))
+}
+
+func (s *Server) getDepartment(w http.ResponseWriter, r *http.Request) {
+	deptId, err := strconv.Atoi(r.PathValue("department"))
+	if err != nil {
+		s.error(w, http.StatusBadRequest, fmt.Errorf("%s is not a valid department", r.PathValue("department")))
+		return
+	}
+	term, err := scrape.ParseTerm(r.PathValue("term"))
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err)
+		return
+	}
+	format, err := parseFormat(r)
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	schedules, err := s.db.LoadDepartments(deptId, []scrape.Term{term})
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Scrape the department if we haven't seen it that term
+	key := fmt.Sprintf("%d/%s", deptId, term)
+	if len(schedules) == 0 && !s.missed(key) {
+		log.Println("Scraping department", deptId, term)
+		schedules, err = scrape.GetDepartment(s.c.Clone(), term, deptId)
+		if err != nil {
+			s.error(w, http.StatusBadGateway, err)
+			return
+		}
+		if len(schedules) == 0 {
+			s.miss(key)
+		} else if err := s.db.SaveDepartments(schedules); err != nil {
+			s.error(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if len(schedules) == 0 {
+		s.error(w, http.StatusNotFound, fmt.Errorf("no sections found for department %d in %s", deptId, term))
+		return
+	}
+
+	s.encode(w, r, format, report.BuildDepartmentRows(schedules))
+}
+
+func (s *Server) listTerms(w http.ResponseWriter, r *http.Request) {
+	terms, err := s.db.Terms()
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].Before(terms[j])
+	})
+	s.json(w, r, terms)
+}
+
+func (s *Server) listDepartments(w http.ResponseWriter, r *http.Request) {
+	departments, err := s.db.Departments()
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.json(w, r, departments)
+}
+
+// scrapeCourse scrapes a course or professor from Banner and saves it,
+// remembering the miss if Banner has nothing
+func (s *Server) scrapeCourse(name string, isProfessor bool) ([]scrape.CourseIsq, []scrape.CourseGrades, []scrape.CourseSchedule, error) {
+	log.Println("Scraping", name)
+	isqs, grades, err := scrape.GetIsqAndGrades(s.c.Clone(), name, isProfessor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(isqs) == 0 && len(grades) == 0 {
+		s.miss(name)
+		return nil, nil, nil, nil
+	}
+	schedules, err := scrape.GetSchedules(s.c.Clone(), scrape.CollectScheduleParams(isqs, grades))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.db.SaveIsqs(isqs); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.db.SaveGrades(grades); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.db.SaveSchedules(schedules); err != nil {
+		return nil, nil, nil, err
+	}
+	return isqs, grades, schedules, nil
+}
+
+// missed reports whether a scrape of a course, professor, or department term
+// found nothing within maxAge
+func (s *Server) missed(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, found := s.misses[key]
+	return found && time.Since(at) < s.maxAge
+}
+
+// miss records that a scrape found nothing
+func (s *Server) miss(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.misses[key] = time.Now()
+}
+
+func parseFormat(r *http.Request) (report.Format, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return report.ParseFormat(format)
+	}
+	return report.Json, nil
+}
+
+func (s *Server) encode(w http.ResponseWriter, r *http.Request, format report.Format, rows interface{}) {
+	var buf bytes.Buffer
+	if err := report.Encode(&buf, rows, format); err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.write(w, r, format.ContentType(), buf.Bytes())
+}
+
+func (s *Server) json(w http.ResponseWriter, r *http.Request, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.write(w, r, "application/json", b)
+}
+
+// write sends a response that clients may cache for maxAge and revalidate
+// with its ETag
+func (s *Server) write(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds())))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) error(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Println("Error:", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/openswoop/isqool/pkg/database"
+	"github.com/openswoop/isqool/pkg/scrape"
+	"github.com/openswoop/isqool/pkg/scrape/scrapetest"
+)
+
+// newTestServer serves a database holding one section of COP2220 and the
+// department's schedule, backed by a Banner that has nothing. The returned
+// counter is how many pages were scraped from Banner.
+func newTestServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var scraped atomic.Int32
+	banner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scraped.Add(1)
+		_, _ = w.Write([]byte("<html><body><div class=\"pagebodydiv\"></div></body></html>"))
+	}))
+	t.Cleanup(banner.Close)
+	institution := scrape.Current
+	scrape.Current.BannerUrl = banner.URL + "/"
+	t.Cleanup(func() { scrape.Current = institution })
+
+	db := database.NewSqlite(filepath.Join(t.TempDir(), "isqool.db"))
+	t.Cleanup(func() { _ = db.Close() })
+	c := scrapetest.Course("COP2220", 10001, "Smith")
+	if err := db.SaveIsqs([]scrape.CourseIsq{scrapetest.Isq(c, 30, 15, [5]float64{50, 50, 0, 0, 0})}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveDepartments([]scrape.DeptSchedule{scrapetest.Section(c)}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(New(db, colly.NewCollector(), time.Hour))
+	t.Cleanup(srv.Close)
+	return srv, &scraped
+}
+
+func TestServerNotFound(t *testing.T) {
+	srv, scraped := newTestServer(t)
+
+	// In order, as misses are remembered
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantScraped bool
+	}{
+		{"stored course", "/api/courses/COP2220", http.StatusOK, false},
+		{"unknown course", "/api/courses/COT9999", http.StatusNotFound, true},
+		{"unknown course again", "/api/courses/COT9999", http.StatusNotFound, false},
+		{"stored department term", "/api/departments/6502/terms/Fall%202023", http.StatusOK, false},
+		{"empty department term", "/api/departments/6502/terms/Spring%202020", http.StatusNotFound, true},
+		{"empty department term again", "/api/departments/6502/terms/Spring%202020", http.StatusNotFound, false},
+		{"unknown instructor", "/api/instructors/Nobody", http.StatusNotFound, false},
+		{"invalid term", "/api/departments/6502/terms/Fall", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := scraped.Load()
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := scraped.Load() > before; got != tt.wantScraped {
+				t.Errorf("scraped Banner = %t, want %t", got, tt.wantScraped)
+			}
+			if tt.wantStatus != http.StatusOK && resp.Header.Get("Cache-Control") != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", resp.Header.Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestServerETag(t *testing.T) {
+	srv, _ := newTestServer(t)
+	resp, err := http.Get(srv.URL + "/api/courses/COP2220")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if got, want := resp.Header.Get("Cache-Control"), "public, max-age=3600"; got != want {
+		t.Errorf("Cache-Control = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"same etag", "/api/courses/COP2220", etag, http.StatusNotModified},
+		{"stale etag", "/api/courses/COP2220", `"stale"`, http.StatusOK},
+		{"other format", "/api/courses/COP2220?format=csv", etag, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
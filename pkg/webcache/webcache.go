@@ -0,0 +1,262 @@
+package webcache
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache is an on-disk cache of HTTP responses. Unlike colly's cache, it
+// covers POST requests and expires entries.
+type Cache struct {
+	Dir string
+	// TTL returns how long the response to a URL stays fresh
+	TTL func(url string) time.Duration
+}
+
+// Entry is a cached response
+type Entry struct {
+	Method     string
+	URL        string
+	Form       []byte // the body of the request, for POSTs
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	FetchedAt  time.Time
+
+	// Where the entry is stored and its size on disk, set when it's read
+	File string
+	Size int64
+}
+
+// Expired reports whether an entry is older than its TTL
+func (c Cache) Expired(e Entry) bool {
+	return time.Since(e.FetchedAt) > c.TTL(e.URL)
+}
+
+// Transport returns a RoundTripper that serves fresh responses from the
+// cache, and otherwise fetches and caches them with next
+func (c Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{c, next}
+}
+
+type transport struct {
+	cache Cache
+	next  http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		return t.next.RoundTrip(req)
+	}
+
+	var form []byte
+	if req.Body != nil {
+		var err error
+		form, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(form))
+	}
+
+	file := t.cache.file(req.Method, req.URL.String(), form)
+	if e, err := readEntry(file); err == nil && !t.cache.Expired(e) {
+		return e.response(req), nil
+	}
+
+	// Only successful responses are cached, and only if they'd stay fresh
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 || t.cache.TTL(req.URL.String()) <= 0 {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := Entry{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Form:       form,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		FetchedAt:  time.Now(),
+	}
+	if err := writeEntry(file, e); err != nil {
+		return nil, err
+	}
+	return e.response(req), nil
+}
+
+// file is where the response to a request is cached. GETs are keyed by URL
+// alone, like colly's cache, and POSTs by their form as well.
+func (c Cache) file(method, url string, form []byte) string {
+	key := url
+	if method != http.MethodGet {
+		key = method + " " + url + "\n" + string(form)
+	}
+	sum := sha1.Sum([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	return filepath.Join(c.Dir, hash[:2], hash)
+}
+
+func (e Entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func readEntry(file string) (Entry, error) {
+	var e Entry
+	f, err := os.Open(file)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&e); err != nil {
+		return e, err
+	}
+	if e.URL == "" {
+		return e, fmt.Errorf("%s is not a cache entry", file)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return e, err
+	}
+	e.File, e.Size = file, info.Size()
+	return e, nil
+}
+
+func writeEntry(file string, e Entry) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		return err
+	}
+	// Write to a temp file and rename it over the entry, so concurrent
+	// writers and readers never see half an entry
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*~")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(e); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// Walk calls fn with every entry in the cache. Files that can't be read as
+// entries, such as those written by colly's own cache, are passed as errors.
+func (c Cache) Walk(fn func(e Entry, err error) error) error {
+	err := filepath.Walk(c.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		e, err := readEntry(path)
+		if err != nil {
+			e = Entry{File: path, Size: info.Size()}
+		}
+		return fn(e, err)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Find returns the entries cached for a URL; there may be several for the
+// different forms POSTed to it
+func (c Cache) Find(url string) ([]Entry, error) {
+	var entries []Entry
+	err := c.Walk(func(e Entry, err error) error {
+		if err == nil && e.URL == url {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Prune removes expired and unreadable entries, returning how many were
+// removed and the bytes freed
+func (c Cache) Prune() (int, int64, error) {
+	var removed int
+	var freed int64
+	err := c.Walk(func(e Entry, err error) error {
+		if err == nil && !c.Expired(e) {
+			return nil
+		}
+		if err := os.Remove(e.File); err != nil {
+			return err
+		}
+		removed++
+		freed += e.Size
+		return nil
+	})
+	return removed, freed, err
+}
+
+// Clear removes every entry
+func (c Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// Stats summarizes the entries of a class of pages
+type Stats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+}
+
+// Stats summarizes the cache by the class of each entry, with unreadable
+// files under the class ""
+func (c Cache) Stats(class func(url string) string) (map[string]*Stats, error) {
+	stats := make(map[string]*Stats)
+	err := c.Walk(func(e Entry, err error) error {
+		key := ""
+		if err == nil {
+			key = class(e.URL)
+		}
+		s, ok := stats[key]
+		if !ok {
+			s = &Stats{}
+			stats[key] = s
+		}
+		s.Entries++
+		s.Bytes += e.Size
+		if err != nil {
+			return nil
+		}
+		if c.Expired(e) {
+			s.Expired++
+		}
+		if s.Oldest.IsZero() || e.FetchedAt.Before(s.Oldest) {
+			s.Oldest = e.FetchedAt
+		}
+		return nil
+	})
+	return stats, err
+}
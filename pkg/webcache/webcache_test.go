@@ -0,0 +1,103 @@
+package webcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	var hits atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		forms      [2]string // of the two requests made, for POSTs
+		ttl        time.Duration
+		wantHits   int32
+		wantCached int
+	}{
+		{"fresh", http.MethodGet, "/ok", [2]string{}, time.Hour, 1, 1},
+		{"expired", http.MethodGet, "/ok", [2]string{}, time.Nanosecond, 2, 1},
+		{"no ttl", http.MethodGet, "/ok", [2]string{}, 0, 2, 0},
+		{"not found", http.MethodGet, "/missing", [2]string{}, time.Hour, 2, 0},
+		{"server error", http.MethodGet, "/error", [2]string{}, time.Hour, 2, 0},
+		{"same form", http.MethodPost, "/ok", [2]string{"term=202380", "term=202380"}, time.Hour, 1, 1},
+		{"other form", http.MethodPost, "/ok", [2]string{"term=202380", "term=202410"}, time.Hour, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := Cache{Dir: t.TempDir(), TTL: func(string) time.Duration { return tt.ttl }}
+			client := &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+			hits.Store(0)
+
+			for _, form := range tt.forms {
+				req, _ := http.NewRequest(tt.method, origin.URL+tt.path, strings.NewReader(form))
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if string(body) != tt.path {
+					t.Errorf("body = %q, want %q", body, tt.path)
+				}
+				time.Sleep(time.Millisecond) // let the shortest TTL pass
+			}
+
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("origin hit %d times, want %d", got, tt.wantHits)
+			}
+			entries, err := cache.Find(origin.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantCached {
+				t.Errorf("cached %d entries, want %d", len(entries), tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	cache := Cache{Dir: t.TempDir(), TTL: func(url string) time.Duration {
+		if strings.HasSuffix(url, "/stale") {
+			return time.Hour
+		}
+		return 24 * time.Hour
+	}}
+	for _, e := range []Entry{
+		{Method: http.MethodGet, URL: "http://banner/fresh", StatusCode: 200, FetchedAt: time.Now().Add(-2 * time.Hour)},
+		{Method: http.MethodGet, URL: "http://banner/stale", StatusCode: 200, FetchedAt: time.Now().Add(-2 * time.Hour)},
+	} {
+		if err := writeEntry(cache.file(e.Method, e.URL, nil), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, _, err := cache.Prune()
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune() removed %d, %v, want 1", removed, err)
+	}
+	for url, want := range map[string]int{"http://banner/fresh": 1, "http://banner/stale": 0} {
+		if entries, _ := cache.Find(url); len(entries) != want {
+			t.Errorf("%s has %d entries after pruning, want %d", url, len(entries), want)
+		}
+	}
+}